@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+
+	"changelog-pr/common"
+	"changelog-pr/provider"
+
+	"github.com/spf13/cobra"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the cache of fetched PR/MR descriptions",
+	Long:  `PR/MR descriptions are cached in ~/.config/changelog-pr/cache between runs`,
+}
+
+// cacheClearCmd represents the cache clear command
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every cached PR/MR description",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := provider.NewCache(cacheDir()).Clear(); err != nil {
+			common.Logger.WithError(err).Fatal("Error clearing the cache")
+		}
+		fmt.Println("The cache has been cleared.")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
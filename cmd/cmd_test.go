@@ -32,7 +32,7 @@ var _ = Describe("Generate", func() {
 		}
 	})
 	BeforeEach(func() {
-		mockGitRepo, err = clprovider.GetProvider(clprovider.MOCK)
+		mockGitRepo, err = clprovider.GetProvider(clprovider.MOCK, "", clprovider.Options{})
 		if err != nil {
 			Expect(err).To(Equal(nil))
 		}
@@ -151,9 +151,9 @@ var _ = Describe("Generate", func() {
 
 		It("create a new changelog, all sections", func() {
 			auth := clprovider.AuthToken{
-				GithubToken: "abcdefghijklmnop",
+				AccessToken: "abcdefghijklmnop",
 			}
-			out, err := mockGitRepo.GetChangeLogFromPR("", "v0.0.1", "v0.2.0", auth, "")
+			out, err := mockGitRepo.GetChangeLogFromPRMR("", "v0.0.1", "v0.2.0", auth, "")
 			if err != nil {
 				Expect(err).To(Equal(nil))
 			}
@@ -161,9 +161,9 @@ var _ = Describe("Generate", func() {
 		})
 		It("create a new changelog, additions only", func() {
 			auth := clprovider.AuthToken{
-				GithubToken: "abcdefghijklmnop",
+				AccessToken: "abcdefghijklmnop",
 			}
-			out, err := mockGitRepo.GetChangeLogFromPR("", "v0.0.2", "v0.2.0", auth, "")
+			out, err := mockGitRepo.GetChangeLogFromPRMR("", "v0.0.2", "v0.2.0", auth, "")
 			if err != nil {
 				Expect(err).To(Equal(nil))
 			}
@@ -172,9 +172,9 @@ var _ = Describe("Generate", func() {
 
 		It("create a new changelog, all sections, to file", func() {
 			auth := clprovider.AuthToken{
-				GithubToken: "abcdefghijklmnop",
+				AccessToken: "abcdefghijklmnop",
 			}
-			_, err := mockGitRepo.GetChangeLogFromPR("", "v0.0.1", "v0.2.0", auth, "/tmp/changelog-pr.md")
+			_, err := mockGitRepo.GetChangeLogFromPRMR("", "v0.0.1", "v0.2.0", auth, "/tmp/changelog-pr.md")
 			if err != nil {
 				Expect(err).To(Equal(nil))
 			}
@@ -187,9 +187,9 @@ var _ = Describe("Generate", func() {
 
 		It("create a new changelog, change section, no closure", func() {
 			auth := clprovider.AuthToken{
-				GithubToken: "abcdefghijklmnop",
+				AccessToken: "abcdefghijklmnop",
 			}
-			out, err := mockGitRepo.GetChangeLogFromPR("", "v0.0.3", "v0.2.0", auth, "")
+			out, err := mockGitRepo.GetChangeLogFromPRMR("", "v0.0.3", "v0.2.0", auth, "")
 			if err != nil {
 				Expect(err).To(Equal(nil))
 			}
@@ -3,65 +3,120 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"strings"
+	"time"
 
 	"changelog-pr/common"
 	"changelog-pr/provider"
 
 	"github.com/blang/semver/v4"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // generateCmd represents the generate command
 var generateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate a Changelog from PR descriptions since a specified TAG",
-	Long: `EXAMPLE:
-	In this example we will look at all PRs that have been merged since the repository
-	was tagged with 'v0.1.0'
+	Long: `The PRs/MRs merged since the --since-tag, or the last semver TAG, are collected and their
+	"## Changelog Inclusions" sections are written out as the changelog of the --release-tag.
+	Without a --release-tag the changes up to HEAD are shown under "## Unreleased".
 
-	  %> changelog-pr generate --path <path/to/git/src> --since-tag v0.1.0 --release-tag v0.2.0
+	Caching, the local provider, Conventional Commits, categories, labels, templates, Keep a
+	Changelog files, the JSON/YAML output schema and merge detection strategies are described
+	in the README: https://github.com/maahsome/changelog-pr#readme
 
 EXAMPLE:
-	In this example the very last semver based TAG will be used as the '--since-tag'
+	In this example we will look at all PRs that have been merged since the repository
+	was tagged with 'v0.1.0'
 
-	  %> changelog-pr generate --path . --release-tag v0.2.1
+	  %> changelog-pr generate --path <path/to/git/src> --since-tag v0.1.0 --release-tag v0.2.0
 
 EXAMPLE:
-	In this example we will create a <SEMVER>.md changelog file.  If the release file already exists,
-	data will be appended to the already existing file, allowing one to update a master changelog.md
-	file
+	In this example we will create a <SEMVER>.md changelog file, using the very last semver based
+	TAG as the '--since-tag'.  An existing "## <release-tag>" section of the file is replaced.
 
 	  %> RELEASE_VERSION="v0.2.1"
-	  %> changelog-pr generate --path . --release-tag ${RELEASE_VERSION} --file changelog/${RELEASE_VERSION.md}
+	  %> changelog-pr generate --path . --release-tag ${RELEASE_VERSION} --file changelog/${RELEASE_VERSION}.md
 
 EXAMPLE:
 	In this example, the repository is private and requires a Personal Access Token to access the PR information.
 
-	  %> # fetch your personal access token from whereever you store your secrets
-	  %> GIT_PAT=$(security find-generic-password -l "git_pat" -w scripting.keychain-db)
 	  %> changelog-pr generate --path . --release-tag "v0.2.3" --gh-token ${GIT_PAT}
+
+EXAMPLE:
+	Other tooling can read the changelog as JSON or YAML, see the README for the schema.
+
+	  %> changelog-pr generate --path . --release-tag "v0.2.3" --output-format json
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		srcPath, _ := cmd.Flags().GetString("path")
 		sinceTag, _ := cmd.Flags().GetString("since-tag")
 		releaseTag, _ := cmd.Flags().GetString("release-tag")
 		changelogFile, _ := cmd.Flags().GetString("file")
+		templateFile, _ := cmd.Flags().GetString("template")
+		if !cmd.Flags().Changed("template") && viper.IsSet("template") {
+			templateFile = viper.GetString("template")
+		}
+		outputFormat, _ := cmd.Flags().GetString("output-format")
+		if !cmd.Flags().Changed("output-format") && viper.IsSet("outputformat") {
+			outputFormat = viper.GetString("outputformat")
+		}
+		outputFormat = strings.ToLower(outputFormat)
+		switch outputFormat {
+		case common.MARKDOWN, common.JSON, common.YAML:
+		default:
+			common.Logger.Fatal(fmt.Sprintf("Unsupported output format %s, please use markdown, json or yaml", outputFormat))
+		}
+		keepAChangelog, _ := cmd.Flags().GetBool("keep-a-changelog")
+		if !cmd.Flags().Changed("keep-a-changelog") && viper.IsSet("keepachangelog") {
+			keepAChangelog = viper.GetBool("keepachangelog")
+		}
+		compareURL, _ := cmd.Flags().GetString("compare-url")
+		if !cmd.Flags().Changed("compare-url") && viper.IsSet("compareurl") {
+			compareURL = viper.GetString("compareurl")
+		}
+		if keepAChangelog && (len(changelogFile) == 0 || outputFormat != common.MARKDOWN) {
+			common.Logger.Fatal("Please specify a --file in the markdown output format with --keep-a-changelog")
+		}
+		onExisting, _ := cmd.Flags().GetString("on-existing")
+		if !cmd.Flags().Changed("on-existing") && viper.IsSet("onexisting") {
+			onExisting = viper.GetString("onexisting")
+		}
+		onExisting = strings.ToLower(onExisting)
+		switch onExisting {
+		case common.REPLACE, common.SKIP, common.FAIL, common.APPEND:
+		default:
+			common.Logger.Fatal(fmt.Sprintf("Unsupported --on-existing %s, please use replace, skip, fail or append", onExisting))
+		}
 
-		if len(sinceTag) > 0 {
-			_, sterr := semver.Parse(strings.Replace(sinceTag, "v", "", 1))
-			if sterr != nil {
-				common.Logger.Fatal(fmt.Sprintf("Error parsing SemVer for %s", sinceTag))
+		if len(releaseTag) > 0 {
+			_, rterr := semver.Parse(strings.Replace(releaseTag, "v", "", 1))
+			if rterr != nil {
+				common.Logger.Fatal(fmt.Sprintf("Error parsing SemVer for %s", releaseTag))
 			}
+		} else {
+			releaseTag = common.UNRELEASED
 		}
-		_, rterr := semver.Parse(strings.Replace(releaseTag, "v", "", 1))
-		if rterr != nil {
-			common.Logger.Fatal(fmt.Sprintf("Error parsing SemVer for %s", releaseTag))
+
+		opts := collectOptions(cmd)
+		opts.Format = outputFormat
+		opts.OnExisting = onExisting
+		if len(templateFile) > 0 {
+			t, err := common.LoadTemplate(templateFile)
+			if err != nil {
+				common.Logger.WithError(err).Fatal("Failed to load the changelog template")
+			}
+			opts.Template = t
+		}
+		if keepAChangelog {
+			opts.KeepAChangelog = &common.KeepAChangelog{CompareURL: compareURL, OnExisting: onExisting}
 		}
 
-		glog, err := generateLog(srcPath, sinceTag, releaseTag, changelogFile)
+		glog, err := generateLog(srcPath, sinceTag, releaseTag, changelogFile, opts)
 		if err != nil {
-			common.Logger.WithError(err).Error("Error generating the changelog")
+			common.Logger.WithError(err).Fatal("Error generating the changelog")
 		}
 
 		fmt.Println(glog)
@@ -69,52 +124,201 @@ EXAMPLE:
 	},
 }
 
-func generateLog(src string, sTag string, rTag string, logFile string) (string, error) {
+// collectOptions - The options of collecting a changelog, from the flags added by
+// addCollectFlags and the config file
+func collectOptions(cmd *cobra.Command) provider.Options {
+	sinceTag, _ := cmd.Flags().GetString("since-tag")
+	strategies, _ := cmd.Flags().GetStringSlice("strategies")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	if !cmd.Flags().Changed("concurrency") && viper.IsSet("concurrency") {
+		concurrency = viper.GetInt("concurrency")
+	}
+	retries, _ := cmd.Flags().GetInt("retries")
+	retryMaxWait, _ := cmd.Flags().GetDuration("retry-max-wait")
+	noCache, _ := cmd.Flags().GetBool("no-cache")
+	commitURL, _ := cmd.Flags().GetString("commit-url")
+	if !cmd.Flags().Changed("commit-url") && viper.IsSet("commiturl") {
+		commitURL = viper.GetString("commiturl")
+	}
+	parser, _ := cmd.Flags().GetString("parser")
+	if !cmd.Flags().Changed("parser") && viper.IsSet("parser") {
+		parser = viper.GetString("parser")
+	}
+	if parser != provider.MARKDOWN && parser != provider.CONVENTIONAL {
+		common.Logger.Fatal(fmt.Sprintf("Unsupported parser %s, please use markdown or conventional", parser))
+	}
+	prTemplate, _ := cmd.Flags().GetString("pr-template")
+	if !cmd.Flags().Changed("pr-template") && viper.IsSet("prtemplate") {
+		prTemplate = viper.GetString("prtemplate")
+	}
+	fallback, _ := cmd.Flags().GetString("fallback")
+	if !cmd.Flags().Changed("fallback") && viper.IsSet("fallback") {
+		fallback = viper.GetString("fallback")
+	}
+	if len(fallback) > 0 && fallback != provider.TITLE {
+		common.Logger.Fatal(fmt.Sprintf("Unsupported fallback %s, please use title", fallback))
+	}
+	fallbackCategory, _ := cmd.Flags().GetString("fallback-category")
+	if !cmd.Flags().Changed("fallback-category") && viper.IsSet("fallbackcategory") {
+		fallbackCategory = viper.GetString("fallbackcategory")
+	}
+	recordDir, _ := cmd.Flags().GetString("record")
+	replayDir, _ := cmd.Flags().GetString("replay")
+	if len(recordDir) > 0 && len(replayDir) > 0 {
+		common.Logger.Fatal("Please specify only one of --record and --replay")
+	}
+
+	if len(sinceTag) > 0 {
+		_, sterr := semver.Parse(strings.Replace(sinceTag, "v", "", 1))
+		if sterr != nil {
+			common.Logger.Fatal(fmt.Sprintf("Error parsing SemVer for %s", sinceTag))
+		}
+	}
+
+	opts := provider.Options{
+		Strategies:   viper.GetStringMapStringSlice("strategies"),
+		Concurrency:  concurrency,
+		Retries:      retries,
+		RetryMaxWait: retryMaxWait,
+		Record:       recordDir,
+		Replay:       replayDir,
+		CommitURL:    commitURL,
+		Parser:       parser,
+		Categories:   categories(),
+		Fallback:     fallback,
+	}
+	if len(fallbackCategory) > 0 {
+		if !hasCategory(opts.Categories, fallbackCategory) && !strings.EqualFold(fallbackCategory, common.UncategorizedCategory.Name) {
+			common.Logger.Fatal(fmt.Sprintf("The fallback category %q is not a changelog category", fallbackCategory))
+		}
+		opts.FallbackCategory = fallbackCategory
+	}
+	opts.Labels = labelRules(opts.Categories)
+	opts.Boilerplate = common.NewBoilerplate(common.PRTemplate(opts.Categories))
+	if len(prTemplate) > 0 {
+		data, err := ioutil.ReadFile(prTemplate)
+		if err != nil {
+			common.Logger.WithError(err).Fatal("Failed to read the PR template")
+		}
+		opts.Boilerplate.Add(string(data))
+	}
+	opts.ConventionalTypes = conventionalTypes(opts.Categories)
+	if len(strategies) > 0 {
+		opts.Strategies = map[string][]string{"default": strategies}
+	}
+	if !noCache {
+		opts.Cache = provider.NewCache(cacheDir())
+	}
+	return opts
+}
+
+func generateLog(src string, sTag string, rTag string, logFile string, opts provider.Options) (string, error) {
+
+	gp, auth, err := getGitProvider(opts)
+	if err != nil {
+		return "", err
+	}
+
+	chlog, err := gp.GetChangeLogFromPRMR(src, sTag, rTag, auth, logFile)
+	if err != nil {
+		return "", err
+	}
+
+	return chlog, nil
+}
+
+// getGitProvider - The configured git provider and its access token
+func getGitProvider(opts provider.Options) (provider.Provider, provider.AuthToken, error) {
 
 	var (
-		err   error
-		chlog string
-		gp    provider.Provider
+		err error
+		gp  provider.Provider
 	)
 
 	var auth provider.AuthToken
 	switch strings.ToLower(gitProvider) {
 	case "github":
-		gp, err = provider.GetProvider(provider.GITHUB, ghHost)
+		gp, err = provider.GetProvider(provider.GITHUB, ghHost, opts)
 		if err != nil {
-			return "", errors.New("failed to provision git provider")
+			return nil, auth, errors.New("failed to provision git provider")
 		}
 		auth = provider.AuthToken{
 			AccessToken: ghToken,
 		}
 	case "gitlab":
 		common.Logger.Trace("Host", glHost)
-		gp, err = provider.GetProvider(provider.GITLAB, glHost)
+		gp, err = provider.GetProvider(provider.GITLAB, glHost, opts)
 		if err != nil {
-			return "", errors.New("failed to provision git provider")
+			return nil, auth, errors.New("failed to provision git provider")
 		}
 		auth = provider.AuthToken{
 			AccessToken: glToken,
 		}
+	case "bitbucket":
+		gp, err = provider.GetProvider(provider.BITBUCKET, bbHost, opts)
+		if err != nil {
+			return nil, auth, errors.New("failed to provision git provider")
+		}
+		auth = provider.AuthToken{
+			AccessToken: bbToken,
+		}
+	case "gitea":
+		gp, err = provider.GetProvider(provider.GITEA, gtHost, opts)
+		if err != nil {
+			return nil, auth, errors.New("failed to provision git provider")
+		}
+		auth = provider.AuthToken{
+			AccessToken: gtToken,
+		}
+	case "azure":
+		gp, err = provider.GetProvider(provider.AZURE, azHost, opts)
+		if err != nil {
+			return nil, auth, errors.New("failed to provision git provider")
+		}
+		auth = provider.AuthToken{
+			AccessToken: azToken,
+		}
+	case "local":
+		gp, err = provider.GetProvider(provider.LOCAL, "", opts)
+		if err != nil {
+			return nil, auth, errors.New("failed to provision git provider")
+		}
 	default:
-		return "", errors.New("unsupported provider")
+		return nil, auth, errors.New("unsupported provider")
 	}
 
-	chlog, err = gp.GetChangeLogFromPRMR(src, sTag, rTag, auth, logFile)
-	if err != nil {
-		return "", errors.New("failed generation of changelog")
-	}
-
-	return chlog, nil
+	return gp, auth, nil
 }
 
 func init() {
 	rootCmd.AddCommand(generateCmd)
-	generateCmd.Flags().StringP("path", "p", "", "Specify the path to the git source directory")
-	generateCmd.Flags().StringP("since-tag", "t", "", "Specify the git TAG to go back to and process PR descriptions")
-	generateCmd.Flags().StringP("release-tag", "r", "", "Specify the new release TAG")
+	addCollectFlags(generateCmd)
+	generateCmd.Flags().StringP("release-tag", "r", "", "Specify the new release TAG, the changes since the last TAG are shown as Unreleased without one")
 	generateCmd.Flags().StringP("file", "f", "", "Specify an output file to save the changelog to")
-	generateCmd.MarkFlagRequired("path")
-	// generateCmd.MarkFlagRequired("since-tag")
-	generateCmd.MarkFlagRequired("release-tag")
+	generateCmd.Flags().String("template", "", "Specify a Go text/template file to render the changelog with, see 'changelog-pr template show-default'")
+	generateCmd.Flags().String("output-format", common.MARKDOWN, "Specify the output format: 'markdown', or 'json' and 'yaml' for the full changelog data, see 'changelog-pr generate --help'")
+	generateCmd.Flags().Bool("keep-a-changelog", false, "Maintain --file in the keepachangelog.com format, replacing the section of the release and updating the comparison links")
+	generateCmd.Flags().String("compare-url", "", "Specify the link pattern comparing two tags for --keep-a-changelog, {from} and {to} are replaced with the tags")
+	generateCmd.Flags().String("on-existing", common.REPLACE, "Specify what happens when --file already has a section for the release: 'replace' it, 'skip' the file, 'fail' or 'append' another copy")
+}
+
+// addCollectFlags - Add the flags that select and configure what is collected
+// into a changelog
+func addCollectFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("path", "p", "", "Specify the path to the git source directory")
+	cmd.Flags().StringP("since-tag", "t", "", "Specify the git TAG to go back to and process PR descriptions")
+	cmd.Flags().StringSlice("strategies", []string{}, "Specify the merge detection strategies to use (merge, squash, lookup), overriding the 'strategies' config")
+	cmd.Flags().Int("concurrency", 4, "Specify the number of PR/MR descriptions to fetch, or commits to look up, at the same time")
+	cmd.Flags().Int("retries", 5, "Specify the number of times a rate limited or failed API call is retried")
+	cmd.Flags().Duration("retry-max-wait", 15*time.Minute, "Specify the longest time to wait before retrying an API call, including waits for a rate limit reset")
+	cmd.Flags().Bool("no-cache", false, "Do not use or update the cache of fetched PR/MR descriptions")
+	cmd.Flags().String("commit-url", "", "Specify the link pattern for commits read by the local provider, {sha} and {short} are replaced with the commit hash")
+	cmd.Flags().String("parser", provider.MARKDOWN, "Specify how changelog entries are read: 'markdown' sections of the PR/MR description, or 'conventional' commit titles")
+	cmd.Flags().String("pr-template", "", "Specify the PR/MR template of the repository, its placeholder text is left out of the changelog")
+	cmd.Flags().String("fallback", "", "Specify 'title' to add the title of PRs/MRs without changelog entries to the changelog")
+	cmd.Flags().String("fallback-category", "", "Specify the category titles are added to by --fallback, 'Uncategorized' when empty")
+	cmd.Flags().String("record", "", "Specify a directory to save every provider API request and response to, as fixture files")
+	cmd.Flags().String("replay", "", "Specify a directory of recorded fixture files to serve provider API responses from, without network access")
+	cmd.MarkFlagRequired("path")
+	// cmd.MarkFlagRequired("since-tag")
 }
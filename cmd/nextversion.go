@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+
+	"changelog-pr/common"
+	"changelog-pr/provider"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// nextVersionCmd represents the next-version command
+var nextVersionCmd = &cobra.Command{
+	Use:   "next-version",
+	Short: "Recommend the next SemVer from the changes since the last TAG",
+	Long: `The changelog is collected like 'changelog-pr generate' does, from the last TAG (or
+	--since-tag) up to HEAD, and the version after that TAG is recommended from its entries.
+	Breaking Changes and Removals call for a major bump, a minor one before 1.0.0, Additions and
+	Deprecations for a minor bump and everything else for a patch.  The largest bump wins.  The
+	version is printed on the first line, followed by the reasons.
+
+EXAMPLE:
+	  %> changelog-pr next-version --path .
+	  v0.3.0
+	  minor bump from v0.2.1
+	    Additions: 2 entries, minor
+	    Bug Fixes: 1 entry, patch
+
+	  %> changelog-pr generate --path . --release-tag $(changelog-pr next-version --path . --short)
+
+EXAMPLE:
+	A prerelease identifier recommends release candidates, the first one of a version is '-rc.1'
+	and the next TAG after 'v0.3.0-rc.1' is 'v0.3.0-rc.2'.  Without --prerelease the version of
+	the release candidate is recommended for the release.
+
+	  %> changelog-pr next-version --path . --prerelease rc
+
+EXAMPLE:
+	The bump of a category can be changed in the config file, categories without a rule call for a
+	patch.
+
+	  # ~/.config/changelog-pr/config.yaml
+	  bump:
+	    deprecations: patch
+	    security: minor`,
+	Run: func(cmd *cobra.Command, args []string) {
+		srcPath, _ := cmd.Flags().GetString("path")
+		sinceTag, _ := cmd.Flags().GetString("since-tag")
+		prerelease, _ := cmd.Flags().GetString("prerelease")
+		short, _ := cmd.Flags().GetBool("short")
+
+		rules, err := common.MergeBumpRules(viper.GetStringMapString("bump"))
+		if err != nil {
+			common.Logger.WithError(err).Fatal("Error reading the 'bump' config")
+		}
+
+		current := sinceTag
+		if len(current) == 0 {
+			h, err := provider.OpenHistory(srcPath)
+			if err != nil {
+				common.Logger.WithError(err).Fatal("Error reading the git history")
+			}
+			latest, err := h.LatestTag()
+			if err != nil {
+				common.Logger.WithError(err).Fatal("Error finding the last TAG")
+			}
+			current = latest.Name().Short()
+		}
+
+		gp, auth, err := getGitProvider(collectOptions(cmd))
+		if err != nil {
+			common.Logger.WithError(err).Fatal("Error collecting the changelog")
+		}
+		changeLog, err := gp.GetChangelog(srcPath, current, common.UNRELEASED, auth)
+		if err != nil {
+			common.Logger.WithError(err).Fatal("Error collecting the changelog")
+		}
+
+		next, err := common.RecommendVersion(current, changeLog, rules, prerelease)
+		if err != nil {
+			common.Logger.WithError(err).Fatal("Error recommending the next version")
+		}
+
+		fmt.Println(next.Next)
+		if short {
+			return
+		}
+		fmt.Printf("%s bump from %s\n", next.Bump, next.Current)
+		for _, reason := range next.Reasons {
+			fmt.Printf("  %s\n", reason)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(nextVersionCmd)
+	addCollectFlags(nextVersionCmd)
+	nextVersionCmd.Flags().String("prerelease", "", "Specify a prerelease identifier, e.g. 'rc', to recommend the next prerelease of the version")
+	nextVersionCmd.Flags().Bool("short", false, "Print only the version")
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"changelog-pr/common"
+	"changelog-pr/provider"
 
 	"github.com/spf13/viper"
 )
@@ -20,6 +21,12 @@ var (
 	ghHost      string
 	glToken     string
 	glHost      string
+	bbToken     string
+	bbHost      string
+	gtToken     string
+	gtHost      string
+	azToken     string
+	azHost      string
 	semVer      string
 	gitCommit   string
 	buildDate   string
@@ -33,8 +40,9 @@ var rootCmd = &cobra.Command{
 	Long: `Given a previous git TAG, locate all of the PRs since that TAG, and parse the
 	description of the PR for specific MD sections and build a changelog from the data.
 
-	Currently there is only support for GitHub repositories, though adding different git
-	providers should be fairly straight forward.
+	GitHub, GitLab, Bitbucket Cloud, Gitea/Forgejo and Azure DevOps repositories are supported,
+	adding different git providers should be fairly straight forward.  The 'local' provider reads
+	the sections from the commit messages instead, for repositories without a PR workflow.
 
 	Use the 'changelog-pr template' command to display the PR TEMPLATE data`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
@@ -58,7 +66,7 @@ var rootCmd = &cobra.Command{
 
 		common.NewLogger(ll, logFile)
 
-		if os.Args[1] != "version" {
+		if os.Args[1] != "version" && cmd.Parent() != cacheCmd && cmd != templateShowDefaultCmd {
 			if len(gitProvider) > 0 {
 				viper.Set("gitprovider", gitProvider)
 				verr := viper.WriteConfig()
@@ -104,7 +112,7 @@ var rootCmd = &cobra.Command{
 					if len(glTokenFromEnv) > 0 {
 						glToken = glTokenFromEnv
 					} else {
-						if gitProvider == "gitlab" {
+						if gitProvider == "gitlab" && tokenRequired(cmd) {
 							logrus.Fatal("Please provide a gitlab-token via --gitlab-token or GITLAB_TOKEN environment variable")
 						}
 					}
@@ -141,12 +149,123 @@ var rootCmd = &cobra.Command{
 					if len(ghTokenFromEnv) > 0 {
 						ghToken = ghTokenFromEnv
 					} else {
-						if gitProvider == "github" {
+						if gitProvider == "github" && tokenRequired(cmd) {
 							logrus.Fatal("Please provide a github-token via --github-token or GITHUB_TOKEN environment variable")
 						}
 					}
 				}
 			}
+
+			if len(bbHost) > 0 {
+				viper.Set("bitbuckethost", bbHost)
+				verr := viper.WriteConfig()
+				if verr != nil {
+					logrus.WithError(verr).Info("Failed to write config")
+				}
+			} else {
+				bbHostFromConfig := viper.GetString("bitbuckethost")
+				if len(bbHostFromConfig) > 0 {
+					bbHost = bbHostFromConfig
+				} else {
+					bbHost = "bitbucket.org"
+				}
+			}
+
+			if len(bbToken) > 0 {
+				viper.Set("bitbuckettoken", bbToken)
+				verr := viper.WriteConfig()
+				if verr != nil {
+					logrus.WithError(verr).Info("Failed to write config")
+				}
+			} else {
+				bbTokenFromConfig := viper.GetString("bitbuckettoken")
+				if len(bbTokenFromConfig) > 0 {
+					bbToken = bbTokenFromConfig
+				} else {
+					bbTokenFromEnv := os.Getenv("BITBUCKET_TOKEN")
+					if len(bbTokenFromEnv) > 0 {
+						bbToken = bbTokenFromEnv
+					} else {
+						if gitProvider == "bitbucket" && tokenRequired(cmd) {
+							logrus.Fatal("Please provide a bitbucket-token via --bitbucket-token or BITBUCKET_TOKEN environment variable")
+						}
+					}
+				}
+			}
+
+			if len(gtHost) > 0 {
+				viper.Set("giteahost", gtHost)
+				verr := viper.WriteConfig()
+				if verr != nil {
+					logrus.WithError(verr).Info("Failed to write config")
+				}
+			} else {
+				gtHostFromConfig := viper.GetString("giteahost")
+				if len(gtHostFromConfig) > 0 {
+					gtHost = gtHostFromConfig
+				} else {
+					gtHost = "gitea.com"
+				}
+			}
+
+			if len(gtToken) > 0 {
+				viper.Set("giteatoken", gtToken)
+				verr := viper.WriteConfig()
+				if verr != nil {
+					logrus.WithError(verr).Info("Failed to write config")
+				}
+			} else {
+				gtTokenFromConfig := viper.GetString("giteatoken")
+				if len(gtTokenFromConfig) > 0 {
+					gtToken = gtTokenFromConfig
+				} else {
+					gtTokenFromEnv := os.Getenv("GITEA_TOKEN")
+					if len(gtTokenFromEnv) > 0 {
+						gtToken = gtTokenFromEnv
+					} else {
+						if gitProvider == "gitea" && tokenRequired(cmd) {
+							logrus.Fatal("Please provide a gitea-token via --gitea-token or GITEA_TOKEN environment variable")
+						}
+					}
+				}
+			}
+
+			if len(azHost) > 0 {
+				viper.Set("azurehost", azHost)
+				verr := viper.WriteConfig()
+				if verr != nil {
+					logrus.WithError(verr).Info("Failed to write config")
+				}
+			} else {
+				azHostFromConfig := viper.GetString("azurehost")
+				if len(azHostFromConfig) > 0 {
+					azHost = azHostFromConfig
+				} else {
+					azHost = "dev.azure.com"
+				}
+			}
+
+			if len(azToken) > 0 {
+				viper.Set("azuretoken", azToken)
+				verr := viper.WriteConfig()
+				if verr != nil {
+					logrus.WithError(verr).Info("Failed to write config")
+				}
+			} else {
+				azTokenFromConfig := viper.GetString("azuretoken")
+				if len(azTokenFromConfig) > 0 {
+					azToken = azTokenFromConfig
+				} else {
+					azTokenFromEnv := os.Getenv("AZURE_DEVOPS_TOKEN")
+					if len(azTokenFromEnv) > 0 {
+						azToken = azTokenFromEnv
+					} else {
+						if gitProvider == "azure" && tokenRequired(cmd) {
+							logrus.Fatal("Please provide an azure-token via --azure-token or AZURE_DEVOPS_TOKEN environment variable")
+						}
+					}
+				}
+			}
 		}
 	},
 }
@@ -164,13 +283,19 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.changelog-pr.yaml)")
-	rootCmd.PersistentFlags().StringVarP(&gitProvider, "git-provider", "g", "", "git source provider (github)")
-	rootCmd.PersistentFlags().StringP("log-file", "l", "", "Specify a log file to log events to, default to no logging")
+	rootCmd.PersistentFlags().StringVarP(&gitProvider, "git-provider", "g", "", "git source provider (github, gitlab, bitbucket, gitea, azure, local)")
+	rootCmd.PersistentFlags().StringP("log-file", "l", "", "Specify a log file to log events to, default to stderr")
 	rootCmd.PersistentFlags().StringP("log-level", "v", "", "Specify a log level for logging, default to Warning (Trace, Debug, Info, Warning, Error, Fatal)")
 	rootCmd.PersistentFlags().StringVar(&ghToken, "github-token", "", "Specify your GitHub personal access token")
-	rootCmd.PersistentFlags().StringVar(&ghHost, "github-host", "", "Specify your GitHub Host")
+	rootCmd.PersistentFlags().StringVar(&ghHost, "github-host", "", "Specify your GitHub Host, API calls for a GitHub Enterprise Server host use https://<host>/api/v3")
 	rootCmd.PersistentFlags().StringVar(&glToken, "gitlab-token", "", "Specify your GitLab personal access token")
 	rootCmd.PersistentFlags().StringVar(&glHost, "gitlab-host", "", "Specify your GitLab Host")
+	rootCmd.PersistentFlags().StringVar(&bbToken, "bitbucket-token", "", "Specify your Bitbucket access token, or 'username:app_password'")
+	rootCmd.PersistentFlags().StringVar(&bbHost, "bitbucket-host", "", "Specify your Bitbucket Host")
+	rootCmd.PersistentFlags().StringVar(&gtToken, "gitea-token", "", "Specify your Gitea/Forgejo access token")
+	rootCmd.PersistentFlags().StringVar(&gtHost, "gitea-host", "", "Specify your Gitea/Forgejo Host")
+	rootCmd.PersistentFlags().StringVar(&azToken, "azure-token", "", "Specify your Azure DevOps personal access token")
+	rootCmd.PersistentFlags().StringVar(&azHost, "azure-host", "", "Specify your Azure DevOps Host")
 
 }
 
@@ -211,6 +336,69 @@ func initConfig() {
 	}
 }
 
+// tokenRequired - Replayed runs serve recorded API responses, so need no token
+func tokenRequired(cmd *cobra.Command) bool {
+	replay := cmd.Flags().Lookup("replay")
+	return replay == nil || len(replay.Value.String()) == 0
+}
+
+// cacheDir - Where fetched PR/MR descriptions are cached between runs
+func cacheDir() string {
+	home, err := os.UserHomeDir()
+	cobra.CheckErr(err)
+	return fmt.Sprintf("%s/.config/changelog-pr/cache", home)
+}
+
+// categories - The changelog categories, the built in ones merged with those in
+// the 'categories' config
+func categories() []common.Category {
+	var configured []common.Category
+	if err := viper.UnmarshalKey("categories", &configured); err != nil {
+		common.Logger.WithError(err).Fatal("Failed to read the 'categories' config")
+	}
+	merged, err := common.MergeCategories(configured)
+	if err != nil {
+		common.Logger.WithError(err).Fatal("Failed to read the 'categories' config")
+	}
+	return merged
+}
+
+// hasCategory - Whether cats has a category with the given name
+func hasCategory(cats []common.Category, name string) bool {
+	for _, c := range cats {
+		if strings.EqualFold(c.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// labelRules - The 'labels' config, every label category must be a changelog category
+func labelRules(cats []common.Category) provider.LabelRules {
+	var rules provider.LabelRules
+	if err := viper.UnmarshalKey("labels", &rules); err != nil {
+		common.Logger.WithError(err).Fatal("Failed to read the 'labels' config")
+	}
+	for _, r := range rules.Categories {
+		if !hasCategory(cats, r.Category) {
+			common.Logger.Fatal(fmt.Sprintf("The 'labels' config files %s under %q, which is not a changelog category", r.Label, r.Category))
+		}
+	}
+	return rules
+}
+
+// conventionalTypes - The 'conventional' config merged over the default types,
+// every type must be mapped onto a changelog category
+func conventionalTypes(cats []common.Category) map[string]string {
+	configured := viper.GetStringMapString("conventional")
+	for t, category := range configured {
+		if len(category) > 0 && !hasCategory(cats, category) {
+			common.Logger.Fatal(fmt.Sprintf("The 'conventional' config files %s under %q, which is not a changelog category", t, category))
+		}
+	}
+	return common.MergeConventionalTypes(configured)
+}
+
 func createRestrictedConfigFile(fileName string) {
 	if _, err := os.Stat(fileName); err != nil {
 		if os.IsNotExist(err) {
@@ -3,6 +3,8 @@ package cmd
 import (
 	"fmt"
 
+	"changelog-pr/common"
+
 	"github.com/spf13/cobra"
 )
 
@@ -10,35 +12,46 @@ import (
 var templateCmd = &cobra.Command{
 	Use:   "template",
 	Short: "Output the markdown that needs to be added to the PR template",
-	Long:  `This command outputs the markdown that is added to the '.github/PULL_REQUEST_TEMPLATE.md' file`,
+	Long: `This command outputs the markdown that is added to the '.github/PULL_REQUEST_TEMPLATE.md' file.
+	There is a section for every changelog category, including those added in the config file.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(`## Changelog Inclusions
-
-<!-- Text Entered in these sections will appear as it is written, MD formatted -->
-<!-- Avoid using the # character as the first character on any line, a trim is -->
-<!-- performed on each line when checking for markdown section tags -->
-- base feature note
-  - **BREAKING** note on base feature
-  - Basically whatever formatting we have here, just plain-text
-	%> command example
-- next feature
-  - note on next feature
-<!-- If there is NO text in a section, no entries will be collected for that section -->
-
-### Additions
-
-### Changes
-
-### Fixes
-
-### Deprecated
-
-### Removed
+		fmt.Println(common.PRTemplate(categories()))
+	},
+}
 
-### Breaking Changes`)
+// templateShowDefaultCmd represents the template show-default command
+var templateShowDefaultCmd = &cobra.Command{
+	Use:   "show-default",
+	Short: "Output the built in changelog template, a starting point for --template",
+	Long: `This command outputs the Go text/template used to render the changelog.  Save it to a file,
+	change it and pass the file to 'changelog-pr generate --template <file>', or set 'template' in
+	the config file.
+
+	The template is executed with the Changelog: .Version and .Sections, where every section has
+	the category .Name and .Title and its .Entries.  .AllEntries holds the entries of every section.
+	An entry has a .Description, a .Scope and the request it came from: .Kind, .Number, .Title,
+	.URL, .Author, .MergedAt, .Labels and .MergeSHA.  {{ template "link" . }} renders the link
+	of an entry.
+
+	Helper functions:
+	  sortBy "number|title|author|date" ENTRIES   a sorted copy of the entries
+	  reverse ENTRIES                             the entries in reverse order
+	  groupByAuthor ENTRIES                       groups with a .Key and .Entries, by author
+	  groupByLabel ENTRIES                        groups by label, an entry is in every group of its labels
+	  groupByScope ENTRIES                        groups by Conventional Commits scope
+	  date "2006-01-02" TIME                      a formatted time, empty when unknown
+	  linkify "https://host/issues/{number}" TEXT link the #123 issue references in TEXT
+	  join ", " LIST, lower TEXT, upper TEXT, trim TEXT
+
+EXAMPLE:
+	  %> changelog-pr template show-default > changelog.tmpl
+	  %> changelog-pr generate --path . --release-tag v0.2.0 --template changelog.tmpl`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Print(common.DefaultTemplate())
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templateShowDefaultCmd)
 }
@@ -0,0 +1,224 @@
+package common
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Boilerplate - The placeholder text of PR/MR templates, which is left out of the
+// changelog when a contributor does not replace it
+type Boilerplate struct {
+	blocks map[string]bool
+}
+
+// - item, * item, + item or 1. item
+var listItemRegex = regexp.MustCompile(`^(?:[-*+]|\d+[.)])(?:[ \t]|$)`)
+
+// ``` or ~~~
+var fenceRegex = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+
+// NewBoilerplate - The placeholder text of the given templates, the blocks of
+// their "Changelog Inclusions" sections that are not a heading or a comment
+func NewBoilerplate(templates ...string) *Boilerplate {
+	b := &Boilerplate{blocks: map[string]bool{}}
+	for _, t := range templates {
+		b.Add(t)
+	}
+	return b
+}
+
+// Add - Add the placeholder text of a template, the text elsewhere in the
+// template is not changelog entries and is left alone
+func (b *Boilerplate) Add(template string) {
+	source := stripComments(normalizeNewlines(template))
+	found := headings(goldmark.DefaultParser().Parse(text.NewReader(source)), source)
+
+	for i, h := range found {
+		if !strings.EqualFold(h.text, inclusionsHeading) {
+			continue
+		}
+		offset := h.end
+		for _, next := range found[i+1:] {
+			if next.level <= h.level {
+				break
+			}
+			b.addBlocks(string(source[offset:next.start]))
+			offset = next.end
+		}
+		end := len(source)
+		for _, next := range found[i+1:] {
+			if next.level <= h.level {
+				end = next.start
+				break
+			}
+		}
+		if offset < end {
+			b.addBlocks(string(source[offset:end]))
+		}
+	}
+}
+
+// addBlocks - Add the blocks of the markdown between two headings
+func (b *Boilerplate) addBlocks(markdown string) {
+	lines := strings.Split(markdown, "\n")
+	for _, block := range blocks(lines) {
+		b.blocks[blockKey(lines[block[0]:block[1]])] = true
+	}
+}
+
+// blockKey - The text of a block without the indentation of its lines
+func blockKey(lines []string) string {
+	trimmed := make([]string, len(lines))
+	for i, line := range lines {
+		trimmed[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(trimmed, "\n")
+}
+
+// blocks - The start and end line of the blocks of markdown placeholder text is
+// compared by: a fenced code block, a top level list item with the lines nested
+// in it, or a paragraph
+func blocks(lines []string) [][2]int {
+	found := [][2]int{}
+	blank := func(i int) bool { return len(strings.TrimSpace(lines[i])) == 0 }
+	indented := func(i int) bool { return strings.HasPrefix(lines[i], " ") || strings.HasPrefix(lines[i], "\t") }
+
+	for i := 0; i < len(lines); {
+		if blank(i) {
+			i++
+			continue
+		}
+		start := i
+		i++
+		switch {
+		case fenceRegex.MatchString(lines[start]):
+			fence := fenceRegex.FindStringSubmatch(lines[start])[1]
+			for i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+				i++
+			}
+			if i < len(lines) {
+				i++
+			}
+		case listItemRegex.MatchString(lines[start]):
+			for i < len(lines) {
+				if blank(i) {
+					// a blank line only continues the item when an indented line follows
+					next := i
+					for next < len(lines) && blank(next) {
+						next++
+					}
+					if next == len(lines) || !indented(next) {
+						break
+					}
+					i = next
+				}
+				if !indented(i) {
+					break
+				}
+				i++
+			}
+		default:
+			for i < len(lines) && !blank(i) && !listItemRegex.MatchString(lines[i]) && !fenceRegex.MatchString(lines[i]) {
+				i++
+			}
+		}
+		found = append(found, [2]int{start, i})
+	}
+	return found
+}
+
+// clean - Remove HTML comments and blocks of placeholder text from the markdown
+// of a section, reporting whether any placeholder text was found
+func (b *Boilerplate) clean(markdown string) (string, bool) {
+	source := stripComments([]byte(markdown))
+
+	lines := strings.Split(string(source), "\n")
+	removed := make([]bool, len(lines))
+	templated := false
+	for _, block := range blocks(lines) {
+		if b.blocks[blockKey(lines[block[0]:block[1]])] {
+			templated = true
+			for i := block[0]; i < block[1]; i++ {
+				removed[i] = true
+			}
+		}
+	}
+
+	kept := []string{}
+	for i, line := range lines {
+		if removed[i] {
+			continue
+		}
+		// do not leave a run of blank lines where placeholder text was removed
+		if templated && len(strings.TrimSpace(line)) == 0 && len(kept) > 0 && len(strings.TrimSpace(kept[len(kept)-1])) == 0 {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	cleaned := strings.Join(kept, "\n")
+	return sectionText([]byte(cleaned), 0, len(cleaned)), templated
+}
+
+// normalizeNewlines - Replace \r\n and \r line endings with \n
+func normalizeNewlines(s string) []byte {
+	return []byte(strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\r", "\n"))
+}
+
+// stripComments - Remove the HTML comments of a markdown document.  Comments in
+// code spans and code blocks are kept, and a comment block is removed together
+// with the blank line following it
+func stripComments(source []byte) []byte {
+	type span struct{ start, stop int }
+	spans := []span{}
+
+	doc := goldmark.DefaultParser().Parse(text.NewReader(source))
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch v := n.(type) {
+		case *ast.HTMLBlock:
+			if v.HTMLBlockType != ast.HTMLBlockType2 || v.Lines().Len() == 0 {
+				break
+			}
+			start := lineStart(source, v.Lines().At(0).Start)
+			stop := v.Lines().At(v.Lines().Len() - 1).Stop
+			if v.HasClosure() {
+				stop = v.ClosureLine.Stop
+			}
+			stop = lineEnd(source, stop-1)
+			if (start == 0 || bytes.HasSuffix(source[:start], []byte("\n\n"))) && stop < len(source) && source[stop] == '\n' {
+				stop++
+			}
+			spans = append(spans, span{start, stop})
+		case *ast.RawHTML:
+			if v.Segments.Len() == 0 {
+				break
+			}
+			first, last := v.Segments.At(0), v.Segments.At(v.Segments.Len()-1)
+			if bytes.HasPrefix(source[first.Start:], []byte("<!--")) {
+				spans = append(spans, span{first.Start, last.Stop})
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	if len(spans) == 0 {
+		return source
+	}
+	stripped := []byte{}
+	offset := 0
+	for _, s := range spans {
+		if s.start < offset {
+			continue
+		}
+		stripped = append(stripped, source[offset:s.start]...)
+		offset = s.stop
+	}
+	return append(stripped, source[offset:]...)
+}
@@ -0,0 +1,71 @@
+package common_test
+
+import (
+	"bytes"
+
+	"changelog-pr/common"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Boilerplate", func() {
+
+	var (
+		changeLog *common.Changelog
+		logged    *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		common.NewLogger("Warn", "")
+		logged = &bytes.Buffer{}
+		common.Logger.Out = logged
+		changeLog = common.NewChangelog("v0.2.0", nil)
+	})
+
+	It("leaves HTML comments out of the entries", func() {
+		body := "## Changelog Inclusions\n\n### Additions\n\n<!-- describe the addition -->\n\n- A widget <!-- inline note -->\n\n<!--\nmore guidance\n-->\n- A gadget\n\n```html\n<!-- kept in code -->\n```\n"
+		Expect(common.ParseMarkdown(body, common.Request{Kind: "Pull Request", Number: "3"}, changeLog, nil)).To(Succeed())
+		Expect(changeLog.Entries(common.ADDITIONS)).To(HaveLen(1))
+		Expect(changeLog.Entries(common.ADDITIONS)[0].Description).To(Equal("- A widget \n\n- A gadget\n\n```html\n<!-- kept in code -->\n```\n"))
+		Expect(logged.String()).To(BeEmpty())
+	})
+
+	It("reports an untouched built in template", func() {
+		Expect(common.ParseMarkdown("## Description\n\nStuff\n\n"+common.PRTemplate(nil), common.Request{Kind: "Pull Request", Number: "4"}, changeLog, nil)).To(Succeed())
+		Expect(changeLog.HasEntries()).To(BeFalse())
+		Expect(logged.String()).To(ContainSubstring("Pull Request #4 looks like an untouched template"))
+	})
+
+	It("removes placeholder text copied into a section", func() {
+		body := "## Changelog Inclusions\n\n### Additions\n\n- base feature note\n  - **BREAKING** note on base feature\n  - Basically whatever formatting we have here, just plain-text\n\t%> command example\n\n### Changes\n\n- next feature\n  - note on next feature\n- Renamed the widget\n"
+		Expect(common.ParseMarkdown(body, common.Request{Kind: "Pull Request", Number: "5"}, changeLog, nil)).To(Succeed())
+		Expect(changeLog.Entries(common.ADDITIONS)).To(BeEmpty())
+		Expect(changeLog.Entries(common.CHANGES)).To(HaveLen(1))
+		Expect(changeLog.Entries(common.CHANGES)[0].Description).To(Equal("- Renamed the widget\n"))
+		Expect(logged.String()).To(ContainSubstring("Pull Request #5: the Additions section only holds template text"))
+		Expect(logged.String()).To(ContainSubstring("Pull Request #5: template text was removed from the Changes section"))
+		Expect(logged.String()).NotTo(ContainSubstring("untouched template"))
+	})
+
+	It("removes the placeholder text of a custom template", func() {
+		custom := "## Summary\n\n_What does this change?_\n\n## Changelog Inclusions\n\n### Additions\n\n- Describe new features here\n\n### Fixes\n\n- Describe bug fixes here\n"
+		boilerplate := common.NewBoilerplate(common.PRTemplate(nil), custom)
+		body := "## Summary\n\nFixes the widget\n\n## Changelog Inclusions\n\n### Additions\n\n- Describe new features here\n\n### Fixes\n\n- Describe bug fixes here\n- Fixed the widget\n"
+		Expect(common.ParseMarkdown(body, common.Request{Kind: "Merge Request", Number: "6"}, changeLog, boilerplate)).To(Succeed())
+		Expect(changeLog.Entries(common.ADDITIONS)).To(BeEmpty())
+		Expect(changeLog.Entries(common.BUGFIXES)).To(HaveLen(1))
+		Expect(changeLog.Entries(common.BUGFIXES)[0].Description).To(Equal("- Fixed the widget\n"))
+	})
+
+	It("keeps entries that only share lines with the template", func() {
+		custom := "## Changelog Inclusions\n\n### Additions\n\n-\n\n```sh\n%> command example\n```\n\n## Checklist\n\n- [ ] Tests added\n"
+		boilerplate := common.NewBoilerplate(custom)
+		body := "## Changelog Inclusions\n\n### Additions\n\n- Added the export command\n  -\n\n```sh\n%> changelog-pr export\n```\n\n- [ ] Tests added\n\n## Checklist\n\n- [ ] Tests added\n"
+		Expect(common.ParseMarkdown(body, common.Request{Kind: "Pull Request", Number: "7"}, changeLog, boilerplate)).To(Succeed())
+		Expect(changeLog.Entries(common.ADDITIONS)).To(HaveLen(1))
+		Expect(changeLog.Entries(common.ADDITIONS)[0].Description).To(Equal("- Added the export command\n  -\n\n```sh\n%> changelog-pr export\n```\n\n- [ ] Tests added\n"))
+		Expect(logged.String()).To(BeEmpty())
+	})
+
+})
@@ -0,0 +1,138 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Category - A section of the changelog, filled from the "### <Heading>" section
+// below "## Changelog Inclusions" in a PR/MR description and rendered as
+// "### <Title>".  Categories are rendered by ascending Order
+type Category struct {
+	// Name identifies the category in the config file and the Conventional
+	// Commits type mapping, e.g. "bugfixes"
+	Name string
+	// Heading is the section heading used in PR/MR descriptions, e.g. "Fixes"
+	Heading string
+	// Title is the heading of the section in the changelog, e.g. "Bug Fixes"
+	Title string
+	Order int
+	// Disabled removes a built in category from the changelog and the PR template
+	Disabled bool
+}
+
+// Built in category names
+const (
+	ADDITIONS    = "additions"
+	CHANGES      = "changes"
+	REMOVALS     = "removals"
+	DEPRECATIONS = "deprecations"
+	BUGFIXES     = "bugfixes"
+	BREAKING     = "breaking"
+)
+
+// DefaultCategories - The built in categories
+var DefaultCategories = []Category{
+	{Name: ADDITIONS, Heading: "Additions", Title: "Additions", Order: 10},
+	{Name: CHANGES, Heading: "Changes", Title: "Changes", Order: 20},
+	{Name: REMOVALS, Heading: "Removed", Title: "Removals", Order: 30},
+	{Name: DEPRECATIONS, Heading: "Deprecated", Title: "Deprecations", Order: 40},
+	{Name: BUGFIXES, Heading: "Fixes", Title: "Bug Fixes", Order: 50},
+	{Name: BREAKING, Heading: "Breaking Changes", Title: "Breaking Changes", Order: 60},
+}
+
+// UncategorizedCategory - Holds the titles of requests without changelog entries,
+// when no other category is chosen for them.  It is not a section of the PR template
+var UncategorizedCategory = Category{Name: "uncategorized", Heading: "Uncategorized", Title: "Uncategorized", Order: 1000}
+
+// MergeCategories - Add the configured categories to the built in ones.  A
+// configured category with the name of a built in one replaces the fields it
+// sets, or removes it when Disabled, a missing Heading or Title defaults to the
+// other, then to the name
+func MergeCategories(configured []Category) ([]Category, error) {
+	categories := make([]Category, len(DefaultCategories))
+	copy(categories, DefaultCategories)
+
+	for _, c := range configured {
+		c.Name = strings.ToLower(strings.TrimSpace(c.Name))
+		if len(c.Name) == 0 {
+			return nil, fmt.Errorf("a changelog category needs a name: %+v", c)
+		}
+		i := categoryIndex(categories, c.Name)
+		if c.Disabled {
+			if i < 0 {
+				return nil, fmt.Errorf("there is no %q changelog category to disable", c.Name)
+			}
+			categories = append(categories[:i], categories[i+1:]...)
+			continue
+		}
+		if i < 0 {
+			if len(c.Heading) == 0 {
+				c.Heading = c.Title
+			}
+			if len(c.Heading) == 0 {
+				c.Heading = c.Name
+			}
+			if len(c.Title) == 0 {
+				c.Title = c.Heading
+			}
+			categories = append(categories, c)
+			continue
+		}
+		if len(c.Heading) > 0 {
+			categories[i].Heading = c.Heading
+		}
+		if len(c.Title) > 0 {
+			categories[i].Title = c.Title
+		}
+		if c.Order != 0 {
+			categories[i].Order = c.Order
+		}
+	}
+
+	return sortCategories(categories), nil
+}
+
+func categoryIndex(categories []Category, name string) int {
+	for i, c := range categories {
+		if strings.EqualFold(c.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sortCategories - A copy of categories ordered by Order, keeping the given order
+// of categories with the same Order
+func sortCategories(categories []Category) []Category {
+	sorted := make([]Category, len(categories))
+	copy(sorted, categories)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Order < sorted[j].Order
+	})
+	return sorted
+}
+
+// PRTemplate - The markdown to add to the PR/MR template, with a section for
+// every category
+func PRTemplate(categories []Category) string {
+	if categories == nil {
+		categories = DefaultCategories
+	}
+	var b strings.Builder
+	b.WriteString(`## Changelog Inclusions
+
+<!-- Text Entered in these sections will appear as it is written, MD formatted -->
+- base feature note
+  - **BREAKING** note on base feature
+  - Basically whatever formatting we have here, just plain-text
+	%> command example
+- next feature
+  - note on next feature
+<!-- If there is NO text in a section, no entries will be collected for that section -->`)
+	for _, c := range sortCategories(categories) {
+		b.WriteString(fmt.Sprintf("\n\n### %s", c.Heading))
+	}
+	return b.String()
+}
@@ -3,28 +3,63 @@ package common
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"text/template"
 )
 
 type Changelog struct {
-	Version      string
-	Additions    []ChangelogEntry
-	Changes      []ChangelogEntry
-	Removals     []ChangelogEntry
-	Deprecations []ChangelogEntry
-	Bugfixes     []ChangelogEntry
-	Breaking     []ChangelogEntry
-	Repo         string
+	Version string
+	// Sections holds the entries of every category, in category order
+	Sections []Section
+	Repo     string
+	// Since is the tag the changes were collected since, empty when not known
+	Since string
+}
+
+// Section - The entries collected for a category
+type Section struct {
+	Category
+	Entries []ChangelogEntry
 }
 
 type ChangelogEntry struct {
 	Description string
-	Link        string
+	// Scope is the Conventional Commits scope, e.g. "api" for "feat(api): ...",
+	// kept so that entries can be grouped by it
+	Scope string
+	Request
 }
 
+// Request - The PR/MR, or commit, a changelog entry was collected from
+type Request struct {
+	// Kind names the type of request, e.g. "Pull Request", "Merge Request" or "Commit"
+	Kind string
+	// Number is the PR/MR number, or the short hash of a commit
+	Number string
+	Title  string
+	URL    string
+	// Author is the login of the author, or the name of a commit author
+	Author string
+	// MergedAt is when the request was merged, zero when it is not known
+	MergedAt time.Time
+	Labels   []string
+	// MergeSHA is the commit the request was merged with
+	MergeSHA string
+}
+
+// linkTemplate - How an entry links to its request, available to every template
+// as {{ template "link" . }}
+const linkTemplate = `{{- define "link" -}}
+{{ if .URL }}[{{ .Kind }} #{{ .Number }}]({{ .URL }}){{ else }}{{ .Kind }} #{{ .Number }}{{ end }}
+{{- end -}}`
+
 // Careful changing this, as it creates quite a bit of work getting the cmd_test.go
 // working.  It seems odd to use this template to generate the output within the
 // test itself, though that would certainly make it "self-updating".
@@ -32,72 +67,229 @@ type ChangelogEntry struct {
 // then define Changelog{...} data inside the test suite and pass that to the template
 // render and then compare.
 // TODO: make this so.
-const changelogTemplate = `## {{ .Version }}
-{{- if or .Additions .Changes .Removals .Deprecations .Bugfixes .Breaking -}}
-{{- with .Additions }}
+const changelogTemplate = linkTemplate + `
+## {{ .Version }}
+{{- if .HasEntries -}}
+{{- range .Sections }}{{ if .Entries }}
 
-### Additions
-{{ range . }}
-{{ if .Link }}#### {{ .Link }}{{ end }}
+### {{ .Title }}
+{{ range .Entries }}
+{{ if .Kind }}#### {{ template "link" . }}{{ end }}
 
 {{ .Description }}
-{{- end }}{{- end }}
-{{- with .Changes }}
+{{- end }}{{- end }}{{- end }}{{- else }}
 
-### Changes
-{{ range . }}
-{{ if .Link }}#### {{ .Link }}{{ end }}
+No changes for this release!{{ end }}
+`
 
-{{ .Description }}
-{{- end }}{{- end }}
-{{- with .Removals }}
+var changelogTmpl = template.Must(NewTemplate("changelog", changelogTemplate))
 
-### Removals
-{{ range . }}
-{{ if .Link }}#### {{ .Link }}{{ end }}
+// DefaultTemplate - The text of the built in changelog template
+func DefaultTemplate() string {
+	return changelogTemplate
+}
 
-{{ .Description }}
-{{- end }}{{- end }}
-{{- with .Deprecations }}
+// NewTemplate - Parse a changelog template, with the TemplateFuncs helpers and
+// the "link" template available to it
+func NewTemplate(name string, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(TemplateFuncs()).Parse(linkTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return t.Parse(text)
+}
 
-### Deprecations
-{{ range . }}
-{{ if .Link }}#### {{ .Link }}{{ end }}
+// LoadTemplate - Parse the changelog template in a file
+func LoadTemplate(path string) (*template.Template, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := NewTemplate(filepath.Base(path), string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the template %s: %v", path, err)
+	}
+	return t, nil
+}
 
-{{ .Description }}
-{{- end }}{{- end }}
-{{- with .Bugfixes }}
+// UNRELEASED - The version of a changelog of the changes since the last release,
+// and the name of their section in a changelog file
+const UNRELEASED = "Unreleased"
 
-### Bug Fixes
-{{ range . }}
-{{ if .Link }}#### {{ .Link }}{{ end }}
+// NewChangelog - An empty changelog with a section for every category, nil
+// categories selects DefaultCategories
+func NewChangelog(version string, categories []Category) *Changelog {
+	if categories == nil {
+		categories = DefaultCategories
+	}
+	c := &Changelog{Version: version}
+	for _, category := range sortCategories(categories) {
+		c.Sections = append(c.Sections, Section{Category: category})
+	}
+	return c
+}
 
-{{ .Description }}
-{{- end }}{{- end }}
-{{- with .Breaking }}
+// AddSection - Add an empty section for a category that has none, in category order
+func (c *Changelog) AddSection(category Category) {
+	if c.Section(category.Name) != nil {
+		return
+	}
+	i := len(c.Sections)
+	for i > 0 && c.Sections[i-1].Order > category.Order {
+		i--
+	}
+	c.Sections = append(c.Sections, Section{})
+	copy(c.Sections[i+1:], c.Sections[i:])
+	c.Sections[i] = Section{Category: category}
+}
 
-### Breaking Changes
-{{ range . }}
-{{ if .Link }}#### {{ .Link }}{{ end }}
+// Unreleased - Whether the changelog holds the changes since the last release,
+// rather than those of a release
+func (c *Changelog) Unreleased() bool {
+	return strings.EqualFold(c.Version, UNRELEASED)
+}
 
-{{ .Description }}
-{{- end }}{{- end }}{{- else }}
+// Section - The section of the named category, nil when there is no such category
+func (c *Changelog) Section(name string) *Section {
+	for i := range c.Sections {
+		if strings.EqualFold(c.Sections[i].Name, name) {
+			return &c.Sections[i]
+		}
+	}
+	return nil
+}
 
-No changes for this release!{{ end }}
-`
+// Entries - The entries of the named category
+func (c *Changelog) Entries(name string) []ChangelogEntry {
+	if s := c.Section(name); s != nil {
+		return s.Entries
+	}
+	return nil
+}
+
+// categories - The categories of the sections
+func (c *Changelog) categories() []Category {
+	categories := make([]Category, len(c.Sections))
+	for i, s := range c.Sections {
+		categories[i] = s.Category
+	}
+	return categories
+}
+
+// Count - The number of entries in every category
+func (c *Changelog) Count() int {
+	count := 0
+	for _, s := range c.Sections {
+		count += len(s.Entries)
+	}
+	return count
+}
 
-var changelogTmpl = template.Must(template.New("changelog").Parse(changelogTemplate))
+// AddTitle - File the title of a request as an entry of the named category, for
+// requests that do not describe their changes
+func (c *Changelog) AddTitle(category string, request Request) error {
+	section := c.Section(category)
+	if section == nil {
+		return fmt.Errorf("there is no %q changelog category", category)
+	}
+	section.Entries = append(section.Entries, ChangelogEntry{
+		Description: fmt.Sprintf("- %s\n", strings.TrimSpace(request.Title)),
+		Request:     request,
+	})
+	return nil
+}
+
+// HasEntries - Whether any category has an entry
+func (c *Changelog) HasEntries() bool {
+	for _, s := range c.Sections {
+		if len(s.Entries) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// AllEntries - The entries of every category, in category order
+func (c *Changelog) AllEntries() []ChangelogEntry {
+	entries := []ChangelogEntry{}
+	for _, s := range c.Sections {
+		entries = append(entries, s.Entries...)
+	}
+	return entries
+}
 
+// Template - Render the changelog with the built in template
 func (c *Changelog) Template() ([]byte, error) {
+	return c.Render(nil)
+}
+
+// Render - Render the changelog with a template, nil selects the built in one
+func (c *Changelog) Render(t *template.Template) ([]byte, error) {
+	if t == nil {
+		t = changelogTmpl
+	}
 	w := &bytes.Buffer{}
-	if err := changelogTmpl.Execute(w, c); err != nil {
+	if err := t.Execute(w, c); err != nil {
 		return nil, err
 	}
 	return w.Bytes(), nil
 }
 
-func (c *Changelog) WriteFile(path string) error {
-	data, err := c.Template()
+// What WriteFile does when the file already has a section for the version
+const (
+	// REPLACE replaces the section, leaving the rest of the file untouched
+	REPLACE = "replace"
+	// SKIP leaves the file unchanged
+	SKIP = "skip"
+	// FAIL returns an error
+	FAIL = "fail"
+	// APPEND adds the changelog again, the section is kept
+	APPEND = "append"
+)
+
+// a "# " or "## " heading, which ends a "## version" section
+var sectionHeadingRegex = regexp.MustCompile(`^##?(\s|$)`)
+
+// versionSection - The lines of the "## version" section, from the heading to the
+// last line before the next "# " or "## " heading that is not blank
+func versionSection(lines []string, version string) (int, int, bool) {
+	start := -1
+	fenced := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+		}
+		if fenced || !sectionHeadingRegex.MatchString(line) {
+			continue
+		}
+		if start >= 0 {
+			return start, sectionEnd(lines, start, i), true
+		}
+		if matches := versionHeadingRegex.FindStringSubmatch(line); matches != nil && sameVersion(matches[1], version) {
+			start = i
+		}
+	}
+	if start >= 0 {
+		return start, sectionEnd(lines, start, len(lines)), true
+	}
+	return 0, 0, false
+}
+
+// sectionEnd - The end of a section before next, leaving out its trailing blank lines
+func sectionEnd(lines []string, start int, next int) int {
+	for next > start+1 && len(strings.TrimSpace(lines[next-1])) == 0 {
+		next--
+	}
+	return next
+}
+
+// WriteFile - Render the changelog with a template, nil selects the built in one,
+// and add it to the start of the file at path.  onExisting selects what happens
+// when the file already has a "## version" section, REPLACE (the default), SKIP,
+// FAIL or APPEND.  A release replaces the "## Unreleased" section, if any, when
+// the file has no section for it
+func (c *Changelog) WriteFile(path string, t *template.Template, onExisting string) error {
+	data, err := c.Render(t)
 	if err != nil {
 		return err
 	}
@@ -109,6 +301,32 @@ func (c *Changelog) WriteFile(path string) error {
 		return ioutil.WriteFile(path, data, 0644)
 	}
 
+	lines := strings.Split(string(normalizeNewlines(string(existingFile))), "\n")
+	start, end, found := versionSection(lines, c.Version)
+	if !found && !c.Unreleased() {
+		// the release promotes the section of the changes that were not released yet
+		start, end, found = versionSection(lines, UNRELEASED)
+		if found {
+			onExisting = REPLACE
+		}
+	}
+	if found {
+		switch strings.ToLower(onExisting) {
+		case REPLACE, "":
+			section := strings.Split(strings.Trim(string(normalizeNewlines(string(data))), "\n"), "\n")
+			updated := append(append(append([]string{}, lines[:start]...), section...), lines[end:]...)
+			return ioutil.WriteFile(path, []byte(strings.Join(updated, "\n")), 0644)
+		case SKIP:
+			Logger.Warn(fmt.Sprintf("%s already has a section for %s, it was left unchanged", path, c.Version))
+			return nil
+		case FAIL:
+			return fmt.Errorf("%s already has a section for %s", path, c.Version)
+		case APPEND:
+		default:
+			return fmt.Errorf("unsupported %q for an existing section, please use replace, skip, fail or append", onExisting)
+		}
+	}
+
 	data = append(data, '\n')
 	data = append(data, existingFile...)
 	return ioutil.WriteFile(path, data, 0644)
@@ -0,0 +1,125 @@
+package common_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"changelog-pr/common"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Writing the changelog file", func() {
+
+	var (
+		dir     string
+		path    string
+		release func(version string, title string) *common.Changelog
+	)
+
+	const existing = `# Project history
+
+## v1.1.0
+
+### Additions
+
+#### Pull Request #2
+
+- Exports
+
+
+## v1.0.0
+
+- Imports
+`
+
+	BeforeEach(func() {
+		common.NewLogger("Warn", "")
+		var err error
+		dir, err = ioutil.TempDir("", "changelog-pr-write")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "CHANGELOG.md")
+		Expect(ioutil.WriteFile(path, []byte(existing), 0644)).To(Succeed())
+		release = func(version string, title string) *common.Changelog {
+			changeLog := common.NewChangelog(version, nil)
+			Expect(changeLog.AddTitle(common.BUGFIXES, common.Request{Kind: "Pull Request", Number: "3", Title: title})).To(Succeed())
+			return changeLog
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	read := func() string {
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	It("adds a new version to the start of the file", func() {
+		Expect(release("v1.2.0", "Newest").WriteFile(path, nil, common.REPLACE)).To(Succeed())
+		Expect(read()).To(Equal("## v1.2.0\n\n### Bug Fixes\n\n#### Pull Request #3\n\n- Newest\n\n\n" + existing))
+	})
+
+	It("replaces the section of the version, leaving the rest untouched", func() {
+		Expect(release("1.1.0", "Regenerated").WriteFile(path, nil, "")).To(Succeed())
+		replaced := `# Project history
+
+## 1.1.0
+
+### Bug Fixes
+
+#### Pull Request #3
+
+- Regenerated
+
+
+## v1.0.0
+
+- Imports
+`
+		Expect(read()).To(Equal(replaced))
+
+		Expect(release("1.1.0", "Regenerated").WriteFile(path, nil, common.REPLACE)).To(Succeed())
+		Expect(read()).To(Equal(replaced))
+
+		Expect(release("v1.0.0", "Last").WriteFile(path, nil, common.REPLACE)).To(Succeed())
+		Expect(read()).To(HaveSuffix("- Regenerated\n\n\n## v1.0.0\n\n### Bug Fixes\n\n#### Pull Request #3\n\n- Last\n"))
+	})
+
+	It("regenerates a file it created", func() {
+		Expect(os.Remove(path)).To(Succeed())
+		Expect(release("v1.2.0", "Newest").WriteFile(path, nil, common.REPLACE)).To(Succeed())
+		created := read()
+		Expect(release("v1.2.0", "Newest").WriteFile(path, nil, common.REPLACE)).To(Succeed())
+		Expect(read()).To(Equal(created))
+	})
+
+	It("writes the unreleased changes and promotes them when the release is cut", func() {
+		Expect(release(common.UNRELEASED, "Pending").WriteFile(path, nil, common.REPLACE)).To(Succeed())
+		Expect(read()).To(Equal("## Unreleased\n\n### Bug Fixes\n\n#### Pull Request #3\n\n- Pending\n\n\n" + existing))
+
+		Expect(release(common.UNRELEASED, "Still pending").WriteFile(path, nil, common.REPLACE)).To(Succeed())
+		Expect(read()).To(Equal("## Unreleased\n\n### Bug Fixes\n\n#### Pull Request #3\n\n- Still pending\n\n\n" + existing))
+
+		Expect(release("v1.2.0", "Released").WriteFile(path, nil, common.FAIL)).To(Succeed())
+		Expect(read()).To(Equal("## v1.2.0\n\n### Bug Fixes\n\n#### Pull Request #3\n\n- Released\n\n\n" + existing))
+	})
+
+	It("skips, fails or appends when asked to", func() {
+		Expect(release("v1.1.0", "Again").WriteFile(path, nil, common.SKIP)).To(Succeed())
+		Expect(read()).To(Equal(existing))
+
+		Expect(release("v1.1.0", "Again").WriteFile(path, nil, common.FAIL)).To(MatchError(path + " already has a section for v1.1.0"))
+		Expect(read()).To(Equal(existing))
+
+		Expect(release("v1.1.0", "Again").WriteFile(path, nil, common.APPEND)).To(Succeed())
+		Expect(read()).To(Equal("## v1.1.0\n\n### Bug Fixes\n\n#### Pull Request #3\n\n- Again\n\n\n" + existing))
+
+		Expect(release("v1.1.0", "Again").WriteFile(path, nil, "merge")).To(MatchError(ContainSubstring(`unsupported "merge"`)))
+	})
+
+})
@@ -0,0 +1,114 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultConventionalTypes - The Conventional Commits type to changelog category
+// mapping used when none is configured, types without a category are left out
+var DefaultConventionalTypes = map[string]string{
+	"feat":      ADDITIONS,
+	"fix":       BUGFIXES,
+	"perf":      CHANGES,
+	"refactor":  CHANGES,
+	"revert":    CHANGES,
+	"deprecate": DEPRECATIONS,
+	"remove":    REMOVALS,
+}
+
+// MergeConventionalTypes - DefaultConventionalTypes with the configured types
+// added, a configured type replaces the default one and an empty category leaves
+// the type out
+func MergeConventionalTypes(configured map[string]string) map[string]string {
+	types := map[string]string{}
+	for t, category := range DefaultConventionalTypes {
+		types[t] = category
+	}
+	for t, category := range configured {
+		t = strings.ToLower(t)
+		if len(category) == 0 {
+			delete(types, t)
+			continue
+		}
+		types[t] = category
+	}
+	return types
+}
+
+// feat(api)!: add the widget endpoint
+var conventionalHeaderRegex = regexp.MustCompile(`^(\w+)(?:\(([^()]*)\))?(!)?: *(.+)$`)
+
+// BREAKING CHANGE: the widget endpoint moved
+var breakingFooterRegex = regexp.MustCompile(`^BREAKING[ -]CHANGE: *(.*)$`)
+
+// breakingFooter - The text of a BREAKING CHANGE footer, which runs until the
+// next blank line
+func breakingFooter(lines []string) (string, bool) {
+	for i, line := range lines {
+		matches := breakingFooterRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if matches == nil {
+			continue
+		}
+		text := []string{}
+		if len(matches[1]) > 0 {
+			text = append(text, matches[1])
+		}
+		for _, next := range lines[i+1:] {
+			if len(strings.TrimSpace(next)) == 0 {
+				break
+			}
+			text = append(text, strings.TrimSpace(next))
+		}
+		return strings.Join(text, " "), true
+	}
+	return "", false
+}
+
+// ParseConventional - Parse a Conventional Commits message, a commit message or a
+// PR/MR title followed by its description, into the changelog.  types maps the
+// commit type onto a changelog category, nil selects DefaultConventionalTypes.
+// A "!" after the type or a BREAKING CHANGE footer files the entry under Breaking
+func ParseConventional(message string, request Request, cl *Changelog, types map[string]string) error {
+	if types == nil {
+		types = DefaultConventionalTypes
+	}
+
+	lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+	Logger.Info(fmt.Sprintf("Parsing %s #%s as a Conventional Commit...", request.Kind, request.Number))
+	Logger.Trace(fmt.Sprintf("Message: %s", message))
+
+	matches := conventionalHeaderRegex.FindStringSubmatch(strings.TrimSpace(lines[0]))
+	if matches == nil {
+		Logger.Debug(fmt.Sprintf("%s #%s is not a Conventional Commit: %s", request.Kind, request.Number, lines[0]))
+		return nil
+	}
+	commitType, scope, bang, description := strings.ToLower(matches[1]), matches[2], matches[3], strings.TrimSpace(matches[4])
+
+	category := types[commitType]
+	footer, breaking := breakingFooter(lines[1:])
+	if breaking || len(bang) > 0 {
+		category = BREAKING
+		if len(footer) > 0 {
+			description = footer
+		}
+	}
+
+	section := cl.Section(category)
+	if section == nil {
+		Logger.Debug(fmt.Sprintf("No changelog category for the %q type of %s #%s", commitType, request.Kind, request.Number))
+		return nil
+	}
+
+	text := fmt.Sprintf("- %s\n", description)
+	if len(scope) > 0 {
+		text = fmt.Sprintf("- **%s:** %s\n", scope, description)
+	}
+	section.Entries = append(section.Entries, ChangelogEntry{
+		Description: text,
+		Scope:       scope,
+		Request:     request,
+	})
+	return nil
+}
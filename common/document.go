@@ -0,0 +1,132 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Output formats of a changelog
+const (
+	MARKDOWN = "markdown"
+	JSON     = "json"
+	YAML     = "yaml"
+)
+
+// SchemaVersion - The version of the Document schema.  It is raised whenever a
+// field is renamed, removed or changes meaning, adding a field keeps the version
+const SchemaVersion = 1
+
+// Document - The changelog as written by the json and yaml output formats.
+//
+//	schemaVersion  the version of this schema, currently 1
+//	version        the release the changelog is for, e.g. "v1.2.0"
+//	repo           the repository, when known
+//	sections       every changelog category in category order, including empty ones
+type Document struct {
+	SchemaVersion int               `json:"schemaVersion" yaml:"schemaVersion"`
+	Version       string            `json:"version" yaml:"version"`
+	Repo          string            `json:"repo,omitempty" yaml:"repo,omitempty"`
+	Sections      []DocumentSection `json:"sections" yaml:"sections"`
+}
+
+// DocumentSection - A changelog category and its entries.
+//
+//	name     the category name used in the config file, e.g. "additions"
+//	heading  the heading of the category in PR/MR descriptions
+//	title    the title of the section in the Markdown changelog
+//	order    the position of the section, lower first
+//	entries  the entries of the category, in the order they were collected
+type DocumentSection struct {
+	Name    string          `json:"name" yaml:"name"`
+	Heading string          `json:"heading" yaml:"heading"`
+	Title   string          `json:"title" yaml:"title"`
+	Order   int             `json:"order" yaml:"order"`
+	Entries []DocumentEntry `json:"entries" yaml:"entries"`
+}
+
+// DocumentEntry - A changelog entry and the request it was collected from.
+//
+//	description  the Markdown text of the entry
+//	scope        the Conventional Commits scope, omitted when there is none
+//	kind         "Pull Request", "Merge Request" or "Commit"
+//	number       the PR/MR number, or the short hash of a commit
+//	title        the PR/MR title, or the subject of a commit
+//	url          the link to the request, omitted when there is none
+//	author       the login of the author, or the name of a commit author
+//	mergedAt     when the request was merged as RFC 3339, omitted when not known
+//	labels       the PR/MR labels, omitted when there are none
+//	mergeSha     the commit the request was merged with, omitted when not known
+type DocumentEntry struct {
+	Description string     `json:"description" yaml:"description"`
+	Scope       string     `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Kind        string     `json:"kind" yaml:"kind"`
+	Number      string     `json:"number" yaml:"number"`
+	Title       string     `json:"title" yaml:"title"`
+	URL         string     `json:"url,omitempty" yaml:"url,omitempty"`
+	Author      string     `json:"author,omitempty" yaml:"author,omitempty"`
+	MergedAt    *time.Time `json:"mergedAt,omitempty" yaml:"mergedAt,omitempty"`
+	Labels      []string   `json:"labels,omitempty" yaml:"labels,omitempty"`
+	MergeSHA    string     `json:"mergeSha,omitempty" yaml:"mergeSha,omitempty"`
+}
+
+// Document - The changelog as a Document of the current SchemaVersion
+func (c *Changelog) Document() Document {
+	d := Document{
+		SchemaVersion: SchemaVersion,
+		Version:       c.Version,
+		Repo:          c.Repo,
+		Sections:      []DocumentSection{},
+	}
+	for _, s := range c.Sections {
+		section := DocumentSection{
+			Name:    s.Name,
+			Heading: s.Heading,
+			Title:   s.Title,
+			Order:   s.Order,
+			Entries: []DocumentEntry{},
+		}
+		for _, e := range s.Entries {
+			entry := DocumentEntry{
+				Description: e.Description,
+				Scope:       e.Scope,
+				Kind:        e.Kind,
+				Number:      e.Number,
+				Title:       e.Title,
+				URL:         e.URL,
+				Author:      e.Author,
+				Labels:      e.Labels,
+				MergeSHA:    e.MergeSHA,
+			}
+			if !e.MergedAt.IsZero() {
+				mergedAt := e.MergedAt.UTC()
+				entry.MergedAt = &mergedAt
+			}
+			section.Entries = append(section.Entries, entry)
+		}
+		d.Sections = append(d.Sections, section)
+	}
+	return d
+}
+
+// Format - Write the changelog in an output format, the template is only used by
+// the markdown format, nil selects the built in one
+func (c *Changelog) Format(format string, t *template.Template) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case MARKDOWN, "":
+		return c.Render(t)
+	case JSON:
+		data, err := json.MarshalIndent(c.Document(), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case YAML:
+		return yaml.Marshal(c.Document())
+	}
+	return nil, fmt.Errorf("unsupported output format %q, please use markdown, json or yaml", format)
+}
@@ -0,0 +1,83 @@
+package common_test
+
+import (
+	"encoding/json"
+	"time"
+
+	"changelog-pr/common"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+)
+
+var _ = Describe("Structured output", func() {
+
+	var changeLog *common.Changelog
+
+	BeforeEach(func() {
+		common.NewLogger("Warn", "")
+		changeLog = common.NewChangelog("v1.2.0", []common.Category{
+			{Name: common.ADDITIONS, Heading: "Additions", Title: "Additions", Order: 10},
+			{Name: common.BUGFIXES, Heading: "Fixes", Title: "Bug Fixes", Order: 20},
+		})
+		Expect(changeLog.AddTitle(common.ADDITIONS, common.Request{Kind: "Pull Request", Number: "12", Title: "Add exports", URL: "https://example.com/pull/12", Author: "zoe", MergedAt: time.Date(2021, 3, 2, 10, 30, 0, 0, time.FixedZone("CET", 3600)), Labels: []string{"ui"}, MergeSHA: "6dcb09b"})).To(Succeed())
+		Expect(changeLog.AddTitle(common.ADDITIONS, common.Request{Kind: "Commit", Number: "abc1234", Title: "Add import"})).To(Succeed())
+	})
+
+	It("writes the changelog as JSON", func() {
+		data, err := changeLog.Format(common.JSON, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(MatchJSON(`{
+			"schemaVersion": 1,
+			"version": "v1.2.0",
+			"sections": [
+				{
+					"name": "additions", "heading": "Additions", "title": "Additions", "order": 10,
+					"entries": [
+						{
+							"description": "- Add exports\n",
+							"kind": "Pull Request",
+							"number": "12",
+							"title": "Add exports",
+							"url": "https://example.com/pull/12",
+							"author": "zoe",
+							"mergedAt": "2021-03-02T09:30:00Z",
+							"labels": ["ui"],
+							"mergeSha": "6dcb09b"
+						},
+						{"description": "- Add import\n", "kind": "Commit", "number": "abc1234", "title": "Add import"}
+					]
+				},
+				{"name": "bugfixes", "heading": "Fixes", "title": "Bug Fixes", "order": 20, "entries": []}
+			]
+		}`))
+	})
+
+	It("writes the same document as YAML", func() {
+		data, err := changeLog.Format(common.YAML, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(HavePrefix("schemaVersion: 1\nversion: v1.2.0\nsections:\n- name: additions\n"))
+
+		var fromYAML, fromJSON common.Document
+		Expect(yaml.Unmarshal(data, &fromYAML)).To(Succeed())
+		data, err = changeLog.Format(common.JSON, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(json.Unmarshal(data, &fromJSON)).To(Succeed())
+		Expect(fromYAML).To(Equal(fromJSON))
+		Expect(fromYAML.Sections[0].Entries[0].MergedAt.Equal(time.Date(2021, 3, 2, 9, 30, 0, 0, time.UTC))).To(BeTrue())
+	})
+
+	It("writes Markdown by default", func() {
+		markdown, err := changeLog.Template()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changeLog.Format("", nil)).To(Equal(markdown))
+		Expect(changeLog.Format(common.MARKDOWN, nil)).To(Equal(markdown))
+	})
+
+	It("reports unknown formats", func() {
+		_, err := changeLog.Format("toml", nil)
+		Expect(err).To(MatchError(`unsupported output format "toml", please use markdown, json or yaml`))
+	})
+
+})
@@ -0,0 +1,164 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// EntryGroup - Entries sharing a key, e.g. the same author
+type EntryGroup struct {
+	Key     string
+	Entries []ChangelogEntry
+}
+
+// #123, but not &#123;, abc#123 or an already linked [#123]
+var issueRefRegex = regexp.MustCompile(`(^|[^\w&\[])#(\d+)\b`)
+
+// the markdown an issue reference is left alone in: inline code, links, e.g.
+// [Pull Request #7](https://...), autolinks and URLs
+var unlinkableRegex = regexp.MustCompile("`[^`]*`|!?\\[[^\\]]*\\]\\([^)]*\\)|<[^<>\\s]+>|[a-zA-Z][a-zA-Z0-9+.-]*://\\S+")
+
+// TemplateFuncs - The helper functions available to changelog templates
+//
+//	sortBy "number|title|author|date" ENTRIES   a sorted copy of the entries
+//	reverse ENTRIES                             the entries in reverse order
+//	groupByAuthor ENTRIES                       []EntryGroup, by author login
+//	groupByLabel ENTRIES                        []EntryGroup, an entry is in the group of every label
+//	groupByScope ENTRIES                        []EntryGroup, by Conventional Commits scope
+//	date "2006-01-02" TIME                      a formatted time, empty when the time is zero
+//	linkify "https://host/issues/{number}" TEXT link the #123 issue references in TEXT
+//	join ", " LIST, lower TEXT, upper TEXT, trim TEXT
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"sortBy":        sortBy,
+		"reverse":       reverseEntries,
+		"groupByAuthor": groupByAuthor,
+		"groupByLabel":  groupByLabel,
+		"groupByScope":  groupByScope,
+		"date":          formatDate,
+		"linkify":       linkify,
+		"join":          func(sep string, list []string) string { return strings.Join(list, sep) },
+		"lower":         strings.ToLower,
+		"upper":         strings.ToUpper,
+		"trim":          strings.TrimSpace,
+	}
+}
+
+// compareNumbers - Order request numbers numerically, falling back to text for
+// commit hashes
+func compareNumbers(a string, b string) bool {
+	x, xerr := strconv.ParseUint(a, 10, 64)
+	y, yerr := strconv.ParseUint(b, 10, 64)
+	if xerr == nil && yerr == nil {
+		return x < y
+	}
+	return a < b
+}
+
+func sortBy(field string, entries []ChangelogEntry) ([]ChangelogEntry, error) {
+	var less func(a, b ChangelogEntry) bool
+	switch strings.ToLower(field) {
+	case "number":
+		less = func(a, b ChangelogEntry) bool { return compareNumbers(a.Number, b.Number) }
+	case "title":
+		less = func(a, b ChangelogEntry) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) }
+	case "author":
+		less = func(a, b ChangelogEntry) bool { return strings.ToLower(a.Author) < strings.ToLower(b.Author) }
+	case "date", "mergedat":
+		less = func(a, b ChangelogEntry) bool { return a.MergedAt.Before(b.MergedAt) }
+	default:
+		return nil, fmt.Errorf("cannot sort entries by %q, use number, title, author or date", field)
+	}
+	sorted := make([]ChangelogEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+	return sorted, nil
+}
+
+func reverseEntries(entries []ChangelogEntry) []ChangelogEntry {
+	reversed := make([]ChangelogEntry, len(entries))
+	for i, e := range entries {
+		reversed[len(entries)-1-i] = e
+	}
+	return reversed
+}
+
+// groupBy - Group entries by their keys, groups are ordered by key and entries
+// keep their order.  Entries without a key are grouped under ""
+func groupBy(entries []ChangelogEntry, keys func(e ChangelogEntry) []string) []EntryGroup {
+	groups := []EntryGroup{}
+	index := map[string]int{}
+	for _, e := range entries {
+		k := keys(e)
+		if len(k) == 0 {
+			k = []string{""}
+		}
+		for _, key := range k {
+			i, ok := index[key]
+			if !ok {
+				i = len(groups)
+				index[key] = i
+				groups = append(groups, EntryGroup{Key: key})
+			}
+			groups[i].Entries = append(groups[i].Entries, e)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	return groups
+}
+
+func groupByAuthor(entries []ChangelogEntry) []EntryGroup {
+	return groupBy(entries, func(e ChangelogEntry) []string {
+		if len(e.Author) == 0 {
+			return nil
+		}
+		return []string{e.Author}
+	})
+}
+
+func groupByLabel(entries []ChangelogEntry) []EntryGroup {
+	return groupBy(entries, func(e ChangelogEntry) []string { return e.Labels })
+}
+
+func groupByScope(entries []ChangelogEntry) []EntryGroup {
+	return groupBy(entries, func(e ChangelogEntry) []string {
+		if len(e.Scope) == 0 {
+			return nil
+		}
+		return []string{e.Scope}
+	})
+}
+
+func formatDate(layout string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// linkify - Turn the #123 issue references in text into markdown links, the
+// {number} placeholder of pattern is replaced with the issue number.  References
+// in inline code, links and URLs are left alone
+func linkify(pattern string, text string) string {
+	link := func(part string) string {
+		return issueRefRegex.ReplaceAllStringFunc(part, func(ref string) string {
+			m := issueRefRegex.FindStringSubmatch(ref)
+			return fmt.Sprintf("%s[#%s](%s)", m[1], m[2], strings.ReplaceAll(pattern, "{number}", m[2]))
+		})
+	}
+
+	var b strings.Builder
+	start := 0
+	for _, span := range unlinkableRegex.FindAllStringIndex(text, -1) {
+		b.WriteString(link(text[start:span[0]]))
+		b.WriteString(text[span[0]:span[1]])
+		start = span[1]
+	}
+	b.WriteString(link(text[start:]))
+	return b.String()
+}
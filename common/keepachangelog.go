@@ -0,0 +1,376 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+// keepAChangelogHeader - The start of a new keepachangelog.com file
+const keepAChangelogHeader = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/),
+and this project adheres to [Semantic Versioning](https://semver.org/spec/v2.0.0.html).`
+
+// ## [1.0.0] - 2017-06-20
+var versionHeadingRegex = regexp.MustCompile(`^##\s+\[?([^\]\s]+)\]?`)
+
+// a link reference definition, e.g. "[1.0.0]: https://github.com/owner/repo/compare/v0.3.0...v1.0.0"
+var linkReferenceRegex = regexp.MustCompile(`^\[([^\]]+)\]:\s*(\S+)\s*$`)
+
+// https://github.com/owner/repo/compare/v0.3.0...v1.0.0
+var compareLinkRegex = regexp.MustCompile(`^(.*/)[^/]+?(\.\.\.?)[^/]+$`)
+
+// the tag at the end of a link, e.g. "v1.0.0" of ".../compare/v0.3.0...v1.0.0" or
+// of ".../releases/tag/v1.0.0"
+var linkTagRegex = regexp.MustCompile(`([^/]+?)/?$`)
+
+// KeepAChangelog - Maintains a changelog file in the keepachangelog.com format,
+// where every release has a "## [version] - date" section below the
+// "## [Unreleased]" one, newest first, and the versions link to a comparison of
+// their changes in link references at the bottom of the file
+type KeepAChangelog struct {
+	// CompareURL is the link pattern of a comparison of two tags, {from} and {to}
+	// are replaced with the tags, e.g. https://github.com/owner/repo/compare/{from}...{to}.
+	// When empty the pattern is taken from the existing comparison links
+	CompareURL string
+	// Date is the release date, zero selects today
+	Date time.Time
+	// OnExisting selects what happens when the file already has a section for the
+	// version, REPLACE (the default), SKIP, FAIL or APPEND
+	OnExisting string
+}
+
+// kacSection - A "## " section of a keepachangelog.com file
+type kacSection struct {
+	name  string
+	lines []string
+}
+
+// kacLink - A link reference definition of a keepachangelog.com file
+type kacLink struct {
+	name string
+	url  string
+}
+
+// kacFile - A keepachangelog.com file split into its parts
+type kacFile struct {
+	header   []string
+	sections []kacSection
+	links    []kacLink
+}
+
+// sameVersion - Whether two section names are the same version, "v1.0.0" and
+// "1.0.0" are
+func sameVersion(a string, b string) bool {
+	if strings.EqualFold(a, b) {
+		return true
+	}
+	va, aerr := semver.ParseTolerant(a)
+	vb, berr := semver.ParseTolerant(b)
+	return aerr == nil && berr == nil && va.Equals(vb)
+}
+
+// parseKeepAChangelog - Split a keepachangelog.com file into the text before the
+// first section, the sections and the link references at the bottom
+func parseKeepAChangelog(text string) *kacFile {
+	lines := strings.Split(strings.TrimRight(string(normalizeNewlines(text)), "\n"), "\n")
+
+	// the link references are the last lines of the file
+	end := len(lines)
+	for i := len(lines) - 1; i >= 0; i-- {
+		if linkReferenceRegex.MatchString(lines[i]) {
+			end = i
+		} else if len(strings.TrimSpace(lines[i])) > 0 {
+			break
+		}
+	}
+
+	f := &kacFile{}
+	for _, line := range lines[end:] {
+		if matches := linkReferenceRegex.FindStringSubmatch(line); matches != nil {
+			f.links = append(f.links, kacLink{name: matches[1], url: matches[2]})
+		}
+	}
+
+	fenced := false
+	for _, line := range lines[:end] {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+		}
+		if matches := versionHeadingRegex.FindStringSubmatch(line); matches != nil && !fenced {
+			f.sections = append(f.sections, kacSection{name: matches[1]})
+		}
+		if len(f.sections) == 0 {
+			f.header = append(f.header, line)
+			continue
+		}
+		s := &f.sections[len(f.sections)-1]
+		s.lines = append(s.lines, line)
+	}
+	return f
+}
+
+// String - The file text, with a blank line between the parts
+func (f *kacFile) String() string {
+	parts := []string{}
+	if header := strings.TrimSpace(strings.Join(f.header, "\n")); len(header) > 0 {
+		parts = append(parts, header)
+	}
+	for _, s := range f.sections {
+		parts = append(parts, strings.TrimRight(strings.Join(s.lines, "\n"), "\n "))
+	}
+	if len(f.links) > 0 {
+		links := []string{}
+		for _, l := range f.links {
+			links = append(links, fmt.Sprintf("[%s]: %s", l.name, l.url))
+		}
+		parts = append(parts, strings.Join(links, "\n"))
+	}
+	return strings.Join(parts, "\n\n") + "\n"
+}
+
+// link - The index of the named link reference, -1 when there is none
+func (f *kacFile) link(name string) int {
+	for i, l := range f.links {
+		if strings.EqualFold(l.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// setLink - Add or change the named link reference
+func (f *kacFile) setLink(name string, url string) {
+	if i := f.link(name); i >= 0 {
+		f.links[i].url = url
+		return
+	}
+	f.links = append(f.links, kacLink{name: name, url: url})
+}
+
+// sortLinks - Order the link references of the sections like the sections, the
+// other link references follow in their original order
+func (f *kacFile) sortLinks() {
+	links := []kacLink{}
+	for _, s := range f.sections {
+		if i := f.link(s.name); i >= 0 {
+			links = append(links, f.links[i])
+			f.links = append(f.links[:i], f.links[i+1:]...)
+		}
+	}
+	f.links = append(links, f.links...)
+}
+
+// compareURL - The comparison link pattern of the existing link references
+func (f *kacFile) compareURL() string {
+	for _, s := range f.sections {
+		i := f.link(s.name)
+		if i < 0 {
+			continue
+		}
+		if matches := compareLinkRegex.FindStringSubmatch(f.links[i].url); matches != nil {
+			return matches[1] + "{from}" + matches[2] + "{to}"
+		}
+	}
+	return ""
+}
+
+// tag - The git tag a section was released as, taken from the end of its link as
+// the heading may leave out the "v" of the tag, e.g. "## [1.1.1]" for v1.1.1.  The
+// section name when it has no link
+func (f *kacFile) tag(name string) string {
+	if i := f.link(name); i >= 0 {
+		if matches := linkTagRegex.FindStringSubmatch(f.links[i].url); matches != nil {
+			tags := regexp.MustCompile(`\.\.\.?`).Split(matches[1], -1)
+			return tags[len(tags)-1]
+		}
+	}
+	return name
+}
+
+// headingName - The section name of a version, following the "v" prefix of the
+// existing version headings, e.g. "1.2.0" for v1.2.0 next to "## [1.1.1]"
+func (f *kacFile) headingName(version string) string {
+	if _, err := semver.ParseTolerant(version); err != nil {
+		return version
+	}
+	bare := strings.TrimLeft(version, "vV")
+	for _, s := range f.sections {
+		if _, err := semver.ParseTolerant(s.name); err != nil {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(s.name), "v") {
+			return "v" + bare
+		}
+		return bare
+	}
+	return version
+}
+
+// versionSection - The index of the section of the version, -1 when there is none
+func (f *kacFile) versionSection(version string) int {
+	for i, s := range f.sections {
+		if sameVersion(s.name, version) {
+			return i
+		}
+	}
+	return -1
+}
+
+// insert - Add the section below the Unreleased section in semver order, above a
+// section of the same version, and return its index
+func (f *kacFile) insert(section kacSection) int {
+	version, err := semver.ParseTolerant(section.name)
+	i := 0
+	for ; i < len(f.sections); i++ {
+		if strings.EqualFold(f.sections[i].name, UNRELEASED) {
+			continue
+		}
+		if existing, perr := semver.ParseTolerant(f.sections[i].name); err == nil && perr == nil && existing.LTE(version) {
+			break
+		}
+		if err != nil {
+			// a version that is not semver is the newest
+			break
+		}
+	}
+	f.sections = append(f.sections, kacSection{})
+	copy(f.sections[i+1:], f.sections[i:])
+	f.sections[i] = section
+	return i
+}
+
+// section - Render the changelog as a "## [name] - date" section
+func (k KeepAChangelog) section(name string, c *Changelog, t *template.Template) (kacSection, error) {
+	data, err := c.Render(t)
+	if err != nil {
+		return kacSection{}, err
+	}
+	date := k.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	heading := fmt.Sprintf("## [%s] - %s", name, date.Format("2006-01-02"))
+	if c.Unreleased() {
+		heading = fmt.Sprintf("## [%s]", name)
+	}
+
+	lines := strings.Split(strings.Trim(string(normalizeNewlines(string(data))), "\n"), "\n")
+	if strings.HasPrefix(lines[0], "## ") {
+		lines[0] = heading
+	} else {
+		lines = append([]string{heading, ""}, lines...)
+	}
+	return kacSection{name: name, lines: lines}, nil
+}
+
+// Update - Add the changelog to the text of a keepachangelog.com file, replacing
+// the section of the same version, and update the comparison links of the version
+// and of the section above it.  The version is compared with the tag the changes
+// were collected since, or with the tag of the section below it, and its heading
+// follows the "v" prefix of the existing headings.  An Unreleased changelog replaces the Unreleased
+// section, and a new release empties it, as it holds the changes of the release
+func (k KeepAChangelog) Update(text string, c *Changelog, t *template.Template) (string, error) {
+	f := parseKeepAChangelog(text)
+	if len(strings.TrimSpace(text)) == 0 {
+		f = parseKeepAChangelog(keepAChangelogHeader + "\n\n## [" + UNRELEASED + "]\n")
+	}
+	i := f.versionSection(c.Version)
+	name := f.headingName(c.Version)
+	if i >= 0 {
+		name = f.sections[i].name
+	}
+	section, err := k.section(name, c, t)
+	if err != nil {
+		return "", err
+	}
+	switch strings.ToLower(k.OnExisting) {
+	case REPLACE, "":
+		if i >= 0 {
+			f.sections[i] = section
+		}
+	case SKIP:
+		if i >= 0 {
+			Logger.Warn(fmt.Sprintf("The changelog already has a section for %s, it was left unchanged", c.Version))
+			return text, nil
+		}
+	case FAIL:
+		if i >= 0 {
+			return "", fmt.Errorf("the changelog already has a section for %s", c.Version)
+		}
+	case APPEND:
+		i = -1
+	default:
+		return "", fmt.Errorf("unsupported %q for an existing section, please use replace, skip, fail or append", k.OnExisting)
+	}
+	if i < 0 {
+		if u := f.versionSection(UNRELEASED); u >= 0 && !c.Unreleased() {
+			// the release promotes the changes that were not released yet
+			f.sections[u].lines = f.sections[u].lines[:1]
+		}
+		i = f.insert(section)
+	}
+
+	compare := k.CompareURL
+	if len(compare) == 0 {
+		compare = f.compareURL()
+	}
+	if len(compare) == 0 {
+		Logger.Warn("No --compare-url was given and the changelog has no comparison links to follow, the links were not updated")
+		return f.String(), nil
+	}
+	link := func(from string, to string) string {
+		return strings.NewReplacer("{from}", from, "{to}", to).Replace(compare)
+	}
+
+	to := c.Version
+	if c.Unreleased() {
+		to = "HEAD"
+	}
+	from := c.Since
+	if len(from) == 0 {
+		for _, older := range f.sections[i+1:] {
+			if _, err := semver.ParseTolerant(older.name); err == nil && !sameVersion(older.name, c.Version) {
+				from = f.tag(older.name)
+				break
+			}
+		}
+	}
+	if len(from) > 0 && !sameVersion(from, c.Version) {
+		f.setLink(name, link(from, to))
+	}
+	if i > 0 {
+		newer := f.sections[i-1].name
+		if strings.EqualFold(newer, UNRELEASED) {
+			f.setLink(newer, link(c.Version, "HEAD"))
+		} else {
+			f.setLink(newer, link(c.Version, f.tag(newer)))
+		}
+	}
+	f.sortLinks()
+	return f.String(), nil
+}
+
+// WriteFile - Add the changelog to the keepachangelog.com file at path, which is
+// created when it does not exist
+func (k KeepAChangelog) WriteFile(path string, c *Changelog, t *template.Template) error {
+	existingFile, err := ioutil.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	text, err := k.Update(string(existingFile), c, t)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(text), 0644)
+}
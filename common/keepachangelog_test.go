@@ -0,0 +1,254 @@
+package common_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"changelog-pr/common"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Keep a Changelog", func() {
+
+	var (
+		kac     common.KeepAChangelog
+		release func(version string, title string) *common.Changelog
+	)
+
+	const existing = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+## [Unreleased]
+
+## [v1.1.0] - 2021-02-01
+
+### Additions
+
+- Exports
+
+## [v1.0.0] - 2021-01-01
+
+### Additions
+
+- Imports
+
+[Unreleased]: https://github.com/owner/repo/compare/v1.1.0...HEAD
+[v1.1.0]: https://github.com/owner/repo/compare/v1.0.0...v1.1.0
+[v1.0.0]: https://github.com/owner/repo/releases/tag/v1.0.0
+`
+
+	BeforeEach(func() {
+		common.NewLogger("Warn", "")
+		kac = common.KeepAChangelog{Date: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)}
+		release = func(version string, title string) *common.Changelog {
+			changeLog := common.NewChangelog(version, nil)
+			Expect(changeLog.AddTitle(common.BUGFIXES, common.Request{Kind: "Pull Request", Number: "3", Title: title})).To(Succeed())
+			return changeLog
+		}
+	})
+
+	It("creates a new file", func() {
+		kac.CompareURL = "https://github.com/owner/repo/compare/{from}...{to}"
+		first := release("v0.1.0", "First")
+		first.Since = "v0.0.1"
+		text, err := kac.Update("", first, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(HavePrefix("# Changelog\n\nAll notable changes"))
+		Expect(text).To(HaveSuffix(`## [Unreleased]
+
+## [v0.1.0] - 2021-03-01
+
+### Bug Fixes
+
+#### Pull Request #3
+
+- First
+
+[Unreleased]: https://github.com/owner/repo/compare/v0.1.0...HEAD
+[v0.1.0]: https://github.com/owner/repo/compare/v0.0.1...v0.1.0
+`))
+	})
+
+	It("adds the newest release below Unreleased and follows the existing links", func() {
+		text, err := kac.Update(existing, release("v1.2.0", "Newest"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(Equal(`# Changelog
+
+All notable changes to this project will be documented in this file.
+
+## [Unreleased]
+
+## [v1.2.0] - 2021-03-01
+
+### Bug Fixes
+
+#### Pull Request #3
+
+- Newest
+
+## [v1.1.0] - 2021-02-01
+
+### Additions
+
+- Exports
+
+## [v1.0.0] - 2021-01-01
+
+### Additions
+
+- Imports
+
+[Unreleased]: https://github.com/owner/repo/compare/v1.2.0...HEAD
+[v1.2.0]: https://github.com/owner/repo/compare/v1.1.0...v1.2.0
+[v1.1.0]: https://github.com/owner/repo/compare/v1.0.0...v1.1.0
+[v1.0.0]: https://github.com/owner/repo/releases/tag/v1.0.0
+`))
+	})
+
+	It("links the tags of the keepachangelog.com example, whose headings leave out the v", func() {
+		// an excerpt of https://keepachangelog.com/en/1.1.0/
+		const example = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/),
+and this project adheres to [Semantic Versioning](https://semver.org/spec/v2.0.0.html).
+
+## [Unreleased]
+
+### Added
+
+- v1.1 Brazilian Portuguese translation.
+
+## [1.1.1] - 2023-03-05
+
+### Added
+
+- Arabic translation (#444).
+
+## [1.1.0] - 2019-02-15
+
+### Added
+
+- Danish translation (#297).
+
+## [0.0.1] - 2014-05-31
+
+### Added
+
+- This CHANGELOG file to hopefully serve as an evolving example of a
+  standardized open source project CHANGELOG.
+
+[unreleased]: https://github.com/olivierlacan/keep-a-changelog/compare/v1.1.1...HEAD
+[1.1.1]: https://github.com/olivierlacan/keep-a-changelog/compare/v1.1.0...v1.1.1
+[1.1.0]: https://github.com/olivierlacan/keep-a-changelog/compare/v0.0.1...v1.1.0
+[0.0.1]: https://github.com/olivierlacan/keep-a-changelog/releases/tag/v0.0.1
+`
+		text, err := kac.Update(example, release("v1.2.0", "Newest"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(ContainSubstring("## [Unreleased]\n\n## [1.2.0] - 2021-03-01\n\n### Bug Fixes\n\n#### Pull Request #3\n\n- Newest\n\n## [1.1.1] - 2023-03-05\n"))
+		Expect(text).To(HaveSuffix(`[unreleased]: https://github.com/olivierlacan/keep-a-changelog/compare/v1.2.0...HEAD
+[1.2.0]: https://github.com/olivierlacan/keep-a-changelog/compare/v1.1.1...v1.2.0
+[1.1.1]: https://github.com/olivierlacan/keep-a-changelog/compare/v1.1.0...v1.1.1
+[1.1.0]: https://github.com/olivierlacan/keep-a-changelog/compare/v0.0.1...v1.1.0
+[0.0.1]: https://github.com/olivierlacan/keep-a-changelog/releases/tag/v0.0.1
+`))
+
+		// the tag the changes were collected since is preferred over the one below
+		patch := release("v1.1.2", "Patch")
+		patch.Since = "v1.1.1-hotfix"
+		text, err = kac.Update(text, patch, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(ContainSubstring("- Newest\n\n## [1.1.2] - 2021-03-01\n"))
+		Expect(text).To(ContainSubstring(`[1.2.0]: https://github.com/olivierlacan/keep-a-changelog/compare/v1.1.2...v1.2.0
+[1.1.2]: https://github.com/olivierlacan/keep-a-changelog/compare/v1.1.1-hotfix...v1.1.2
+[1.1.1]: https://github.com/olivierlacan/keep-a-changelog/compare/v1.1.0...v1.1.1
+`))
+	})
+
+	It("inserts an older release in semver order, following the v prefix of the headings", func() {
+		text, err := kac.Update(existing, release("1.0.1", "Patch"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(ContainSubstring("- Exports\n\n## [v1.0.1] - 2021-03-01\n\n### Bug Fixes\n\n#### Pull Request #3\n\n- Patch\n\n## [v1.0.0] - 2021-01-01\n"))
+		Expect(text).To(HaveSuffix(`[Unreleased]: https://github.com/owner/repo/compare/v1.1.0...HEAD
+[v1.1.0]: https://github.com/owner/repo/compare/1.0.1...v1.1.0
+[v1.0.1]: https://github.com/owner/repo/compare/v1.0.0...1.0.1
+[v1.0.0]: https://github.com/owner/repo/releases/tag/v1.0.0
+`))
+	})
+
+	It("replaces the section of the same version in place", func() {
+		text, err := kac.Update(existing, release("v1.1.0", "Regenerated"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(ContainSubstring("## [Unreleased]\n\n## [v1.1.0] - 2021-03-01\n\n### Bug Fixes\n\n#### Pull Request #3\n\n- Regenerated\n\n## [v1.0.0] - 2021-01-01\n"))
+		Expect(text).NotTo(ContainSubstring("Exports"))
+
+		again, err := kac.Update(text, release("v1.1.0", "Regenerated"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again).To(Equal(text))
+	})
+
+	It("skips, fails or appends when asked to", func() {
+		kac.OnExisting = common.SKIP
+		Expect(kac.Update(existing, release("v1.1.0", "Again"), nil)).To(Equal(existing))
+
+		kac.OnExisting = common.FAIL
+		_, err := kac.Update(existing, release("v1.1.0", "Again"), nil)
+		Expect(err).To(MatchError("the changelog already has a section for v1.1.0"))
+		text, err := kac.Update(existing, release("v1.2.0", "Newest"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(ContainSubstring("## [v1.2.0] - 2021-03-01"))
+
+		kac.OnExisting = common.APPEND
+		text, err = kac.Update(existing, release("v1.1.0", "Again"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(ContainSubstring("## [Unreleased]\n\n## [v1.1.0] - 2021-03-01\n\n### Bug Fixes\n\n#### Pull Request #3\n\n- Again\n\n## [v1.1.0] - 2021-02-01\n"))
+		Expect(text).To(ContainSubstring("[v1.1.0]: https://github.com/owner/repo/compare/v1.0.0...v1.1.0\n"))
+	})
+
+	It("writes the unreleased changes and promotes them when the release is cut", func() {
+		text, err := kac.Update(existing, release(common.UNRELEASED, "Pending"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(ContainSubstring("## [Unreleased]\n\n### Bug Fixes\n\n#### Pull Request #3\n\n- Pending\n\n## [v1.1.0] - 2021-02-01\n"))
+		Expect(text).To(ContainSubstring("[Unreleased]: https://github.com/owner/repo/compare/v1.1.0...HEAD\n"))
+
+		text, err = kac.Update(text, release("v1.2.0", "Pending"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(ContainSubstring("## [Unreleased]\n\n## [v1.2.0] - 2021-03-01\n\n### Bug Fixes\n\n#### Pull Request #3\n\n- Pending\n\n## [v1.1.0] - 2021-02-01\n"))
+		Expect(text).To(ContainSubstring("[Unreleased]: https://github.com/owner/repo/compare/v1.2.0...HEAD\n[v1.2.0]: https://github.com/owner/repo/compare/v1.1.0...v1.2.0\n"))
+	})
+
+	It("adds the unreleased changes to a file without an Unreleased section", func() {
+		text, err := kac.Update("# Changelog\n\n## [v1.0.0] - 2021-01-01\n\n- Imports\n", release(common.UNRELEASED, "Pending"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(HavePrefix("# Changelog\n\n## [Unreleased]\n\n### Bug Fixes\n\n#### Pull Request #3\n\n- Pending\n\n## [v1.0.0] - 2021-01-01\n"))
+	})
+
+	It("ignores headings in code blocks", func() {
+		text, err := kac.Update("# Changelog\n\n## [v1.0.0] - 2021-01-01\n\n```\n## [v2.0.0]\n```\n", release("v1.0.0", "Again"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(Equal("# Changelog\n\n## [v1.0.0] - 2021-03-01\n\n### Bug Fixes\n\n#### Pull Request #3\n\n- Again\n"))
+	})
+
+	It("writes the file", func() {
+		dir, err := ioutil.TempDir("", "changelog-pr-kac")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "CHANGELOG.md")
+		Expect(ioutil.WriteFile(path, []byte(existing), 0644)).To(Succeed())
+
+		Expect(kac.WriteFile(path, release("v1.2.0", "Newest"), nil)).To(Succeed())
+		Expect(kac.WriteFile(path, release("v1.2.0", "Newest"), nil)).To(Succeed())
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		text, err := kac.Update(existing, release("v1.2.0", "Newest"), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(text))
+	})
+
+})
@@ -26,7 +26,8 @@ func NewLogger(logLevel string, logFileName string) {
 	var logWriter io.Writer
 
 	if logFileName == "" {
-		logWriter = os.Stdout
+		// stdout carries the changelog, e.g. "generate > CHANGELOG.md"
+		logWriter = os.Stderr
 	} else {
 		logWriter, err = os.OpenFile(logFileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
 		if err != nil {
@@ -0,0 +1,19 @@
+package common_test
+
+import (
+	"os"
+
+	"changelog-pr/common"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Logger", func() {
+
+	It("keeps the log out of stdout, which carries the changelog", func() {
+		common.NewLogger("Warn", "")
+		Expect(common.Logger.Out).To(BeIdenticalTo(os.Stderr))
+	})
+
+})
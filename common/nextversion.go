@@ -0,0 +1,142 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+// The parts of a semantic version a changelog can call for bumping
+const (
+	MAJOR = "major"
+	MINOR = "minor"
+	PATCH = "patch"
+)
+
+// DefaultBumpRules - The bump the entries of a category call for when no rules
+// are configured, categories without a rule call for a patch
+var DefaultBumpRules = map[string]string{
+	BREAKING:     MAJOR,
+	REMOVALS:     MAJOR,
+	ADDITIONS:    MINOR,
+	DEPRECATIONS: MINOR,
+}
+
+var bumpRank = map[string]int{PATCH: 0, MINOR: 1, MAJOR: 2}
+
+// MergeBumpRules - DefaultBumpRules with the configured rules added, a configured
+// rule for a category replaces the default one
+func MergeBumpRules(configured map[string]string) (map[string]string, error) {
+	rules := map[string]string{}
+	for name, bump := range DefaultBumpRules {
+		rules[name] = bump
+	}
+	for name, bump := range configured {
+		bump = strings.ToLower(bump)
+		if _, ok := bumpRank[bump]; !ok {
+			return nil, fmt.Errorf("unsupported bump %q for the %q category, please use major, minor or patch", bump, name)
+		}
+		rules[strings.ToLower(name)] = bump
+	}
+	return rules, nil
+}
+
+// NextVersion - The version recommended for the release after Current
+type NextVersion struct {
+	Current string
+	Next    string
+	// Bump is the part of the version the changes call for bumping, MAJOR, MINOR
+	// or PATCH
+	Bump string
+	// Reasons explains the recommendation, a line per category with entries
+	Reasons []string
+}
+
+// plural - "1 entry", "2 entries"
+func plural(count int, one string, many string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, one)
+	}
+	return fmt.Sprintf("%d %s", count, many)
+}
+
+// RecommendVersion - The version to release after the current tag, given the
+// changes made since.  rules maps a category onto the bump its entries call for,
+// nil selects DefaultBumpRules, and the largest bump wins.  Before 1.0.0 a major
+// bump is a minor one.  A prerelease identifier, e.g. "rc", recommends the next
+// prerelease of the version, "v1.3.0-rc.1" and then "v1.3.0-rc.2"
+func RecommendVersion(current string, c *Changelog, rules map[string]string, prerelease string) (*NextVersion, error) {
+	if rules == nil {
+		rules = DefaultBumpRules
+	}
+	prefix := ""
+	if strings.HasPrefix(current, "v") {
+		prefix = "v"
+	}
+	version, err := semver.Parse(strings.TrimPrefix(current, "v"))
+	if err != nil {
+		return nil, fmt.Errorf("the TAG %s is not a semantic version: %v", current, err)
+	}
+
+	n := &NextVersion{Current: current, Bump: PATCH}
+	for _, s := range c.Sections {
+		if len(s.Entries) == 0 {
+			continue
+		}
+		bump, ok := rules[strings.ToLower(s.Name)]
+		if !ok {
+			bump = PATCH
+		}
+		if bumpRank[bump] > bumpRank[n.Bump] {
+			n.Bump = bump
+		}
+		n.Reasons = append(n.Reasons, fmt.Sprintf("%s: %s, %s", s.Title, plural(len(s.Entries), "entry", "entries"), bump))
+	}
+	if !c.HasEntries() {
+		n.Reasons = append(n.Reasons, fmt.Sprintf("No changelog entries since %s, patch", current))
+	}
+	if n.Bump == MAJOR && version.Major == 0 {
+		n.Bump = MINOR
+		n.Reasons = append(n.Reasons, "Major changes bump the minor version before 1.0.0")
+	}
+
+	next := semver.Version{Major: version.Major, Minor: version.Minor, Patch: version.Patch}
+	if len(version.Pre) > 0 {
+		// a prerelease leads up to the release of its version, which already
+		// includes a bump that may be large enough
+		switch {
+		case n.Bump == MAJOR && (next.Minor > 0 || next.Patch > 0):
+			next = semver.Version{Major: next.Major + 1}
+		case n.Bump == MINOR && next.Patch > 0:
+			next = semver.Version{Major: next.Major, Minor: next.Minor + 1}
+		default:
+			n.Reasons = append(n.Reasons, fmt.Sprintf("%s is a prerelease of %s%s, which includes a %s bump", current, prefix, next, n.Bump))
+		}
+	} else {
+		switch n.Bump {
+		case MAJOR:
+			next = semver.Version{Major: next.Major + 1}
+		case MINOR:
+			next = semver.Version{Major: next.Major, Minor: next.Minor + 1}
+		default:
+			next.Patch++
+		}
+	}
+
+	if len(prerelease) > 0 {
+		identifier, err := semver.NewPRVersion(prerelease)
+		if err != nil || identifier.IsNum {
+			return nil, fmt.Errorf("the prerelease identifier %q is not valid, use e.g. rc", prerelease)
+		}
+		number := uint64(1)
+		if len(version.Pre) == 2 && version.Pre[0].Compare(identifier) == 0 && version.Pre[1].IsNum &&
+			next.Major == version.Major && next.Minor == version.Minor && next.Patch == version.Patch {
+			number = version.Pre[1].VersionNum + 1
+		}
+		next.Pre = []semver.PRVersion{identifier, {VersionNum: number, IsNum: true}}
+	}
+
+	n.Next = prefix + next.String()
+	return n, nil
+}
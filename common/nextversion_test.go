@@ -0,0 +1,87 @@
+package common_test
+
+import (
+	"changelog-pr/common"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Next version", func() {
+
+	var changes func(categories ...string) *common.Changelog
+
+	BeforeEach(func() {
+		common.NewLogger("Warn", "")
+		changes = func(categories ...string) *common.Changelog {
+			changeLog := common.NewChangelog(common.UNRELEASED, nil)
+			for _, category := range categories {
+				Expect(changeLog.AddTitle(category, common.Request{Kind: "Pull Request", Number: "1", Title: "Change"})).To(Succeed())
+			}
+			return changeLog
+		}
+	})
+
+	next := func(current string, changeLog *common.Changelog, prerelease string) string {
+		n, err := common.RecommendVersion(current, changeLog, nil, prerelease)
+		Expect(err).NotTo(HaveOccurred())
+		return n.Next
+	}
+
+	It("bumps the version by the largest change", func() {
+		Expect(next("v1.2.3", changes(), "")).To(Equal("v1.2.4"))
+		Expect(next("v1.2.3", changes(common.BUGFIXES, common.CHANGES), "")).To(Equal("v1.2.4"))
+		Expect(next("v1.2.3", changes(common.BUGFIXES, common.ADDITIONS), "")).To(Equal("v1.3.0"))
+		Expect(next("1.2.3", changes(common.DEPRECATIONS), "")).To(Equal("1.3.0"))
+		Expect(next("v1.2.3", changes(common.ADDITIONS, common.REMOVALS), "")).To(Equal("v2.0.0"))
+		Expect(next("v1.2.3", changes(common.BREAKING), "")).To(Equal("v2.0.0"))
+	})
+
+	It("bumps the minor version for major changes before 1.0.0", func() {
+		n, err := common.RecommendVersion("v0.4.1", changes(common.BREAKING, common.BUGFIXES, common.BUGFIXES), nil, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n.Next).To(Equal("v0.5.0"))
+		Expect(n.Bump).To(Equal(common.MINOR))
+		Expect(n.Reasons).To(Equal([]string{
+			"Bug Fixes: 2 entries, patch",
+			"Breaking Changes: 1 entry, major",
+			"Major changes bump the minor version before 1.0.0",
+		}))
+	})
+
+	It("follows the configured rules", func() {
+		rules, err := common.MergeBumpRules(map[string]string{"Deprecations": "patch", "changes": "MINOR"})
+		Expect(err).NotTo(HaveOccurred())
+		n, err := common.RecommendVersion("v1.2.3", changes(common.DEPRECATIONS), rules, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n.Next).To(Equal("v1.2.4"))
+		n, err = common.RecommendVersion("v1.2.3", changes(common.CHANGES), rules, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n.Next).To(Equal("v1.3.0"))
+		n, err = common.RecommendVersion("v1.2.3", changes(common.BREAKING), rules, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n.Next).To(Equal("v2.0.0"))
+
+		_, err = common.MergeBumpRules(map[string]string{"changes": "huge"})
+		Expect(err).To(MatchError(`unsupported bump "huge" for the "changes" category, please use major, minor or patch`))
+	})
+
+	It("recommends prereleases", func() {
+		Expect(next("v1.2.3", changes(common.ADDITIONS), "rc")).To(Equal("v1.3.0-rc.1"))
+		Expect(next("v1.3.0-rc.1", changes(common.BUGFIXES), "rc")).To(Equal("v1.3.0-rc.2"))
+		Expect(next("v1.3.0-rc.2", changes(common.ADDITIONS), "rc")).To(Equal("v1.3.0-rc.3"))
+		Expect(next("v1.3.0-rc.2", changes(common.BREAKING), "rc")).To(Equal("v2.0.0-rc.1"))
+		Expect(next("v1.3.0-beta.4", changes(common.BUGFIXES), "rc")).To(Equal("v1.3.0-rc.1"))
+		Expect(next("v1.2.4-rc.1", changes(common.ADDITIONS), "rc")).To(Equal("v1.3.0-rc.1"))
+		Expect(next("v1.3.0-rc.2", changes(common.BUGFIXES), "")).To(Equal("v1.3.0"))
+
+		_, err := common.RecommendVersion("v1.2.3", changes(), nil, "rc.1")
+		Expect(err).To(MatchError(`the prerelease identifier "rc.1" is not valid, use e.g. rc`))
+	})
+
+	It("reports tags that are not a semantic version", func() {
+		_, err := common.RecommendVersion("release-7", changes(), nil, "")
+		Expect(err).To(MatchError(ContainSubstring("the TAG release-7 is not a semantic version")))
+	})
+
+})
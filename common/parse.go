@@ -2,107 +2,191 @@ package common
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
 )
 
-func collectSectionText(cl *Changelog, sectionName string, sectionText string, pr string, requestText string, requestURL string) {
+// inclusionsHeading - The heading whose sub sections hold the changelog entries
+const inclusionsHeading = "Changelog Inclusions"
 
-	switch sectionName {
-	case "## Changelog Inclusions.### Additions":
-		cl.Additions = append(cl.Additions, ChangelogEntry{
-			Description: sectionText,
-			Link:        fmt.Sprintf("[%s #%s](%s)", requestText, pr, requestURL),
-		})
-	case "## Changelog Inclusions.### Changes":
-		cl.Changes = append(cl.Changes, ChangelogEntry{
-			Description: sectionText,
-			Link:        fmt.Sprintf("[%s #%s](%s)", requestText, pr, requestURL),
-		})
-	case "## Changelog Inclusions.### Fixes":
-		cl.Bugfixes = append(cl.Bugfixes, ChangelogEntry{
-			Description: sectionText,
-			Link:        fmt.Sprintf("[%s #%s](%s)", requestText, pr, requestURL),
-		})
-	case "## Changelog Inclusions.### Deprecated":
-		cl.Deprecations = append(cl.Deprecations, ChangelogEntry{
-			Description: sectionText,
-			Link:        fmt.Sprintf("[%s #%s](%s)", requestText, pr, requestURL),
-		})
-	case "## Changelog Inclusions.### Removed":
-		cl.Removals = append(cl.Removals, ChangelogEntry{
-			Description: sectionText,
-			Link:        fmt.Sprintf("[%s #%s](%s)", requestText, pr, requestURL),
-		})
-	case "## Changelog Inclusions.### Breaking Changes":
-		cl.Breaking = append(cl.Breaking, ChangelogEntry{
-			Description: sectionText,
-			Link:        fmt.Sprintf("[%s #%s](%s)", requestText, pr, requestURL),
-		})
+// An ATX heading without text, e.g. "###", which the parser records without a position
+var emptyHeadingRegex = regexp.MustCompile(`^ {0,3}#{1,6}(?:[ \t]+#*)?[ \t]*\n?$`)
 
+// collectSectionText - File the text of a section below "Changelog Inclusions"
+// under the category with that heading
+func collectSectionText(cl *Changelog, heading string, sectionText string, request Request) {
+	for i := range cl.Sections {
+		if strings.EqualFold(heading, cl.Sections[i].Heading) {
+			cl.Sections[i].Entries = append(cl.Sections[i].Entries, ChangelogEntry{
+				Description: sectionText,
+				Request:     request,
+			})
+			return
+		}
 	}
+	Logger.Debug(fmt.Sprintf("No changelog category for the %q section", heading))
+}
 
+// heading - A heading of a markdown document and the bytes of the lines it occupies
+type heading struct {
+	level int
+	text  string
+	start int
+	end   int
 }
 
-func ParseMarkdown(body string, pr string, cl *Changelog, requestText string, requestURL string) error {
+// lineStart - The offset of the start of the line holding offset
+func lineStart(source []byte, offset int) int {
+	for offset > 0 && source[offset-1] != '\n' {
+		offset--
+	}
+	return offset
+}
 
-	var splits []string
-	sections := map[string]string{}
-	depthNames := map[int]string{
-		0: "none",
-		1: "none",
-		2: "none",
-		3: "none",
+// lineEnd - The offset just past the newline ending the line holding offset
+func lineEnd(source []byte, offset int) int {
+	for offset < len(source) && source[offset] != '\n' {
+		offset++
 	}
-	sectionName := ""
-	sectionText := ""
-	currentDepth := 0
-	// Process to extract the data between the ### markdown sections after ## Changelog Inclusions
-	if strings.Contains(body, "\r") {
-		splits = strings.Split(body, "\r")
-	} else {
-		splits = strings.Split(body, "\n")
+	if offset < len(source) {
+		offset++
 	}
-	Logger.Info(fmt.Sprintf("Searching PR#%s for Changelog Inclusions...", pr))
-	Logger.Trace(fmt.Sprintf("Body: %s", body))
-	for _, v := range splits {
-		Logger.Trace(fmt.Sprintf("%s\n", v))
-		if strings.HasPrefix(strings.TrimSpace(v), "#") {
-			// We are at a markdown section marker, if we have section text, we need to capture it
-			if len(sectionName) > 0 && len(sectionText) > 0 {
-				collectSectionText(cl, sectionName, sectionText, pr, requestText, requestURL)
-				sections[sectionName] = sectionText
-				sectionText = ""
-			}
-			// determine our new section as our line starts with a markdown section marker
-			if strings.HasPrefix(strings.TrimSpace(v), "## ") {
-				currentDepth = 2
-				depthNames[currentDepth] = strings.TrimSpace(v)
-				sectionName = strings.TrimSpace(v)
-				Logger.Debug(sectionName)
+	return offset
+}
+
+// headings - The top level headings of a document, in document order.  The AST
+// only knows the position of the heading text, so the start and end of the
+// heading lines are derived from it: setext headings end after the underline,
+// and empty ATX headings are found by scanning from the previous heading
+func headings(doc ast.Node, source []byte) []heading {
+	found := []heading{}
+	cursor := 0
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		h, ok := n.(*ast.Heading)
+		if !ok {
+			if n.Lines().Len() > 0 {
+				cursor = n.Lines().At(n.Lines().Len() - 1).Stop
 			}
-			if strings.HasPrefix(strings.TrimSpace(v), "### ") {
-				currentDepth = 3
-				depthNames[currentDepth] = strings.TrimSpace(v)
-				sectionName = fmt.Sprintf("%s.%s", depthNames[2], strings.TrimSpace(v))
-				Logger.Debug(sectionName)
+			continue
+		}
+		var start, end int
+		if h.Lines().Len() > 0 {
+			first, last := h.Lines().At(0), h.Lines().At(h.Lines().Len()-1)
+			start = lineStart(source, first.Start)
+			end = lineEnd(source, last.Stop)
+			if !strings.HasPrefix(strings.TrimLeft(string(source[start:first.Start]), " "), "#") {
+				// setext heading, the underline follows the text
+				end = lineEnd(source, end)
 			}
 		} else {
-			// We are not at a markdown section, collect the section text
-			if len(sectionName) > 0 {
-				if len(strings.Trim(strings.TrimSpace(v), "\n\r")) > 0 {
-					Logger.Debug(fmt.Sprintf("~%s~", strings.Trim(v, "\n\r")))
-					sectionText += fmt.Sprintf("%s\n", strings.Trim(v, "\n\r"))
+			start = lineStart(source, cursor)
+			for start < len(source) && !emptyHeadingRegex.Match(source[start:lineEnd(source, start)]) {
+				start = lineEnd(source, start)
+			}
+			end = lineEnd(source, start)
+		}
+		found = append(found, heading{
+			level: h.Level,
+			text:  strings.TrimSpace(string(h.Text(source))),
+			start: start,
+			end:   end,
+		})
+		cursor = end
+	}
+	return found
+}
+
+// sectionText - The markdown between two offsets without the surrounding blank
+// lines, otherwise kept as written
+func sectionText(source []byte, start int, end int) string {
+	lines := strings.Split(string(source[start:end]), "\n")
+	for len(lines) > 0 && len(strings.TrimSpace(lines[0])) == 0 {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && len(strings.TrimSpace(lines[len(lines)-1])) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s\n", strings.Join(lines, "\n"))
+}
+
+// ParseMarkdown - Collect the sections nested directly below a "Changelog
+// Inclusions" heading of a PR/MR description into the changelog.  The body is
+// parsed as CommonMark, so # lines in code blocks or HTML comments are not
+// headings, and a section runs until the next heading of the same or a higher
+// level.  The markdown of a section is kept as written, apart from line endings,
+// HTML comments and the placeholder text of boilerplate.  A nil boilerplate
+// selects the built in PR template for the categories of the changelog
+func ParseMarkdown(body string, request Request, cl *Changelog, boilerplate *Boilerplate) error {
+	if boilerplate == nil {
+		boilerplate = NewBoilerplate(PRTemplate(cl.categories()))
+	}
+	source := normalizeNewlines(body)
+	Logger.Info(fmt.Sprintf("Searching %s #%s for Changelog Inclusions...", request.Kind, request.Number))
+	Logger.Trace(fmt.Sprintf("Body: %s", body))
+
+	doc := goldmark.DefaultParser().Parse(text.NewReader(source))
+	found := headings(doc, source)
+
+	// parents holds the enclosing heading of every level
+	parents := []heading{}
+	collected := 0
+	templated := false
+	for i, h := range found {
+		for len(parents) > 0 && parents[len(parents)-1].level >= h.level {
+			parents = parents[:len(parents)-1]
+		}
+		inInclusions := len(parents) > 0 && strings.EqualFold(parents[len(parents)-1].text, inclusionsHeading)
+		parents = append(parents, h)
+
+		if !inInclusions {
+			// the text between the Changelog Inclusions heading and its first section
+			// is where the template keeps its examples
+			if strings.EqualFold(h.text, inclusionsHeading) {
+				end := len(source)
+				if i+1 < len(found) {
+					end = found[i+1].start
+				}
+				if _, isTemplate := boilerplate.clean(sectionText(source, h.end, end)); isTemplate {
+					templated = true
 				}
 			}
+			continue
+		}
+
+		end := len(source)
+		for _, next := range found[i+1:] {
+			if next.level <= h.level {
+				end = next.start
+				break
+			}
+		}
+
+		Logger.Debug(fmt.Sprintf("%s.%s", inclusionsHeading, h.text))
+		text, isTemplate := boilerplate.clean(sectionText(source, h.end, end))
+		if isTemplate {
+			templated = true
+			if len(text) == 0 {
+				Logger.Warn(fmt.Sprintf("%s #%s: the %s section only holds template text, it was left out", request.Kind, request.Number, h.text))
+			} else {
+				Logger.Warn(fmt.Sprintf("%s #%s: template text was removed from the %s section", request.Kind, request.Number, h.text))
+			}
+		}
+		if len(text) > 0 {
+			Logger.Debug(fmt.Sprintf("~%s~", text))
+			collectSectionText(cl, h.text, text, request)
+			collected++
 		}
 	}
-	// If we exit with no enclosing section, where one of our changes sections is last in the description
-	// we need to process that last block of text we collected.
-	if len(sectionName) > 0 && len(sectionText) > 0 {
-		Logger.Info("Collecting text from section", sectionName)
-		collectSectionText(cl, sectionName, sectionText, pr, requestText, requestURL)
-		sections[sectionName] = sectionText
-		sectionText = ""
+
+	if collected == 0 && templated {
+		Logger.Warn(fmt.Sprintf("%s #%s looks like an untouched template, no changelog entries were found", request.Kind, request.Number))
 	}
 
 	return nil
@@ -0,0 +1,68 @@
+package common_test
+
+import (
+	"changelog-pr/common"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseMarkdown", func() {
+
+	var changeLog *common.Changelog
+
+	BeforeEach(func() {
+		common.NewLogger("Warn", "")
+		changeLog = common.NewChangelog("v0.2.0", nil)
+	})
+
+	parse := func(body string) {
+		Expect(common.ParseMarkdown(body, common.Request{Kind: "Pull Request", Number: "7", URL: "https://example.com/pull/7"}, changeLog, nil)).To(Succeed())
+	}
+
+	It("keeps the markdown of a section as written", func() {
+		parse("## Changelog Inclusions\n\n### Additions\n\n- A widget\n  - with *options*\n\n  Another paragraph\n\n\tindented code\n\n### Fixes\n- Fix\n")
+		Expect(changeLog.Entries(common.ADDITIONS)).To(HaveLen(1))
+		Expect(changeLog.Entries(common.ADDITIONS)[0].Description).To(Equal("- A widget\n  - with *options*\n\n  Another paragraph\n\n\tindented code\n"))
+		Expect(changeLog.Entries(common.ADDITIONS)[0].Request).To(Equal(common.Request{Kind: "Pull Request", Number: "7", URL: "https://example.com/pull/7"}))
+		Expect(changeLog.Entries(common.BUGFIXES)[0].Description).To(Equal("- Fix\n"))
+	})
+
+	It("does not treat # lines in code fences as headings", func() {
+		parse("## Changelog Inclusions\n\n### Changes\n\n- Run the script\n\n```sh\n# not a heading\n### Fixes\n```\n\n## Checklist\n\n- [ ] done\n")
+		Expect(changeLog.Entries(common.CHANGES)).To(HaveLen(1))
+		Expect(changeLog.Entries(common.CHANGES)[0].Description).To(Equal("- Run the script\n\n```sh\n# not a heading\n### Fixes\n```\n"))
+		Expect(changeLog.Entries(common.BUGFIXES)).To(BeEmpty())
+	})
+
+	It("does not treat # lines in HTML comments as headings", func() {
+		parse("## Changelog Inclusions\n\n<!--\n### Breaking Changes\n- example\n-->\n\n### Additions\n\n- Real\n")
+		Expect(changeLog.Entries(common.BREAKING)).To(BeEmpty())
+		Expect(changeLog.Entries(common.ADDITIONS)).To(HaveLen(1))
+		Expect(changeLog.Entries(common.ADDITIONS)[0].Description).To(Equal("- Real\n"))
+	})
+
+	It("understands setext headings", func() {
+		parse("Description\n===========\n\nSome text\n\nChangelog Inclusions\n--------------------\n\n### Removed\n\n- The old API\n")
+		Expect(changeLog.Entries(common.REMOVALS)).To(HaveLen(1))
+		Expect(changeLog.Entries(common.REMOVALS)[0].Description).To(Equal("- The old API\n"))
+	})
+
+	It("keeps deeper headings inside a section", func() {
+		parse("# Changelog Inclusions\n\n## Additions\n\n#### Widgets\n\n- A widget\n\n## Deprecated\n\n- The gadget\n\n# Checklist\n\n- done\n")
+		Expect(changeLog.Entries(common.ADDITIONS)).To(HaveLen(1))
+		Expect(changeLog.Entries(common.ADDITIONS)[0].Description).To(Equal("#### Widgets\n\n- A widget\n"))
+		Expect(changeLog.Entries(common.DEPRECATIONS)[0].Description).To(Equal("- The gadget\n"))
+	})
+
+	It("ignores sections outside of Changelog Inclusions", func() {
+		parse("## Description\n\n### Additions\n\n- Not an entry\n\n## Changelog Inclusions\n\n### Additions\n\n###\n\n- After an empty heading\n")
+		Expect(changeLog.HasEntries()).To(BeFalse())
+	})
+
+	It("handles carriage return line endings", func() {
+		parse("## Changelog Inclusions\r\n\r\n### Fixes\r\n\r\n- Fix one\r\n- Fix two\r\n")
+		Expect(changeLog.Entries(common.BUGFIXES)[0].Description).To(Equal("- Fix one\n- Fix two\n"))
+	})
+
+})
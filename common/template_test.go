@@ -0,0 +1,108 @@
+package common_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"changelog-pr/common"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Templates", func() {
+
+	var changeLog *common.Changelog
+
+	BeforeEach(func() {
+		common.NewLogger("Warn", "")
+		changeLog = common.NewChangelog("v1.2.0", nil)
+		Expect(changeLog.AddTitle(common.ADDITIONS, common.Request{Kind: "Pull Request", Number: "12", Title: "Add exports, closes #4", URL: "https://example.com/pull/12", Author: "zoe", MergedAt: time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC), Labels: []string{"ui", "export"}})).To(Succeed())
+		Expect(changeLog.AddTitle(common.ADDITIONS, common.Request{Kind: "Pull Request", Number: "9", Title: "Add import", URL: "https://example.com/pull/9", Author: "adam", MergedAt: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), Labels: []string{"export"}})).To(Succeed())
+		Expect(changeLog.AddTitle(common.BUGFIXES, common.Request{Kind: "Commit", Number: "abc1234", Title: "Fix see [#3](https://example.com/issues/3)", Author: "zoe"})).To(Succeed())
+	})
+
+	render := func(text string) string {
+		t, err := common.NewTemplate("test", text)
+		Expect(err).NotTo(HaveOccurred())
+		out, err := changeLog.Render(t)
+		Expect(err).NotTo(HaveOccurred())
+		return string(out)
+	}
+
+	It("renders the built in template when none is given", func() {
+		out, err := changeLog.Render(nil)
+		Expect(err).NotTo(HaveOccurred())
+		markdown, err := changeLog.Template()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal(markdown))
+		Expect(string(out)).To(ContainSubstring("#### [Pull Request #12](https://example.com/pull/12)\n\n- Add exports, closes #4\n"))
+		Expect(string(out)).To(ContainSubstring("#### Commit #abc1234\n"))
+
+		t, err := common.NewTemplate("default", common.DefaultTemplate())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changeLog.Render(t)).To(Equal(out))
+	})
+
+	It("sorts the entries", func() {
+		Expect(render(`{{ range sortBy "number" .AllEntries }}{{ .Number }} {{ end }}`)).To(Equal("9 12 abc1234 "))
+		Expect(render(`{{ range sortBy "date" .AllEntries }}{{ .Number }} {{ end }}`)).To(Equal("abc1234 9 12 "))
+		Expect(render(`{{ range reverse (sortBy "title" .AllEntries) }}{{ .Number }} {{ end }}`)).To(Equal("abc1234 9 12 "))
+	})
+
+	It("groups the entries by author and label", func() {
+		Expect(render(`{{ range groupByAuthor .AllEntries }}{{ .Key }}:{{ range .Entries }} {{ .Number }}{{ end }};{{ end }}`)).To(Equal("adam: 9;zoe: 12 abc1234;"))
+		Expect(render(`{{ range groupByLabel .AllEntries }}{{ .Key }}:{{ range .Entries }} {{ .Number }}{{ end }};{{ end }}`)).To(Equal(": abc1234;export: 12 9;ui: 12;"))
+	})
+
+	It("formats dates, joins labels and links the entries", func() {
+		Expect(render(`{{ range .AllEntries }}{{ template "link" . }} {{ date "Jan 2, 2006" .MergedAt }} [{{ join "," .Labels }}]
+{{ end }}`)).To(Equal("[Pull Request #12](https://example.com/pull/12) Mar 2, 2021 [ui,export]\n[Pull Request #9](https://example.com/pull/9) Mar 1, 2021 [export]\nCommit #abc1234  []\n"))
+	})
+
+	It("links issue references", func() {
+		Expect(render(`{{ range .AllEntries }}{{ linkify "https://example.com/issues/{number}" .Title }}
+{{ end }}`)).To(Equal("Add exports, closes [#4](https://example.com/issues/4)\nAdd import\nFix see [#3](https://example.com/issues/3)\n"))
+	})
+
+	It("leaves issue references in links, URLs and inline code alone", func() {
+		changeLog = common.NewChangelog("v1.2.0", nil)
+		for _, title := range []string{
+			"Follow up on [Pull Request #7](https://example.com/pull/7) for #8",
+			"See https://example.com/issues/7#12, https://example.com/docs/#16 and <https://example.com/docs/#13>",
+			"Match `#14` in the parser, not #15",
+		} {
+			Expect(changeLog.AddTitle(common.CHANGES, common.Request{Kind: "Pull Request", Number: "1", Title: title})).To(Succeed())
+		}
+		Expect(render(`{{ range .AllEntries }}{{ linkify "https://example.com/issues/{number}" .Title }}
+{{ end }}`)).To(Equal("Follow up on [Pull Request #7](https://example.com/pull/7) for [#8](https://example.com/issues/8)\n" +
+			"See https://example.com/issues/7#12, https://example.com/docs/#16 and <https://example.com/docs/#13>\n" +
+			"Match `#14` in the parser, not [#15](https://example.com/issues/15)\n"))
+	})
+
+	It("reports unknown sort fields", func() {
+		t, err := common.NewTemplate("test", `{{ sortBy "size" .AllEntries }}`)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = changeLog.Render(t)
+		Expect(err).To(MatchError(ContainSubstring(`cannot sort entries by "size"`)))
+	})
+
+	It("loads a template from a file", func() {
+		dir, err := ioutil.TempDir("", "changelog-pr-template")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "changelog.tmpl")
+		Expect(ioutil.WriteFile(path, []byte(`# {{ .Version }}{{ range .Sections }}{{ if .Entries }} {{ .Title }}={{ len .Entries }}{{ end }}{{ end }}`), 0644)).To(Succeed())
+
+		t, err := common.LoadTemplate(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changeLog.Render(t)).To(Equal([]byte("# v1.2.0 Additions=2 Bug Fixes=1")))
+
+		Expect(ioutil.WriteFile(path, []byte(`{{ .Version `), 0644)).To(Succeed())
+		_, err = common.LoadTemplate(path)
+		Expect(err).To(MatchError(ContainSubstring("failed to parse the template")))
+	})
+
+})
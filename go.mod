@@ -4,6 +4,7 @@ go 1.16
 
 require (
 	github.com/blang/semver/v4 v4.0.0
+	github.com/go-git/go-billy/v5 v5.0.0
 	github.com/go-git/go-git/v5 v5.2.0
 	github.com/go-resty/resty/v2 v2.5.0
 	github.com/mitchellh/go-homedir v1.1.0
@@ -12,4 +13,6 @@ require (
 	github.com/sirupsen/logrus v1.2.0
 	github.com/spf13/cobra v1.1.3
 	github.com/spf13/viper v1.7.1
+	github.com/yuin/goldmark v1.4.13
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"changelog-pr/common"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-resty/resty/v2"
+)
+
+// Azure - Structure to hold stuff
+type Azure struct {
+	Provider string
+	Host     string
+	Options  Options
+}
+
+type AzurePullRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Repository  struct {
+		WebURL string `json:"webUrl"`
+	} `json:"repository"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	CreatedBy struct {
+		UniqueName string `json:"uniqueName"`
+	} `json:"createdBy"`
+	ClosedDate      *time.Time `json:"closedDate"`
+	LastMergeCommit struct {
+		CommitID string `json:"commitId"`
+	} `json:"lastMergeCommit"`
+}
+
+// azureRepo - An Azure DevOps Git repository within its organization and project
+type azureRepo struct {
+	provider *Azure
+	auth     AuthToken
+	org      string
+	project  string
+	repo     string
+	client   *client
+}
+
+var azureRemoteRegexes = []*regexp.Regexp{
+	// https://dev.azure.com/{organization}/{project}/_git/{repository}
+	// https://{user}@dev.azure.com/{organization}/{project}/_git/{repository}
+	regexp.MustCompile(`dev\.azure\.com/([^/]+)/([^/]+)/_git/([^/]+?)(?:\.git)?/?$`),
+	// git@ssh.dev.azure.com:v3/{organization}/{project}/{repository}
+	regexp.MustCompile(`ssh\.dev\.azure\.com:v3/([^/]+)/([^/]+)/([^/]+?)(?:\.git)?/?$`),
+	// https://{organization}.visualstudio.com/{project}/_git/{repository}
+	regexp.MustCompile(`//(?:[^@/]+@)?([^./]+)\.visualstudio\.com/(?:DefaultCollection/)?([^/]+)/_git/([^/]+?)(?:\.git)?/?$`),
+	// {organization}@vs-ssh.visualstudio.com:v3/{organization}/{project}/{repository}
+	regexp.MustCompile(`vs-ssh\.visualstudio\.com:v3/([^/]+)/([^/]+)/([^/]+?)(?:\.git)?/?$`),
+}
+
+func parseAzurePRNumber(msg string) (uint, error) {
+	matches := numAzureRegex.FindAllStringSubmatch(msg, 1)
+	if len(matches) == 0 || len(matches[0]) < 2 {
+		return 0, fmt.Errorf("could not find PR number in commit message")
+	}
+	u64, err := strconv.ParseUint(matches[0][1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse PR number %q from commit message: %v", matches[0][1], err)
+	}
+	return uint(u64), nil
+}
+
+func getOrganizationProjectRepository(url string) (string, string, string, error) {
+	for _, re := range azureRemoteRegexes {
+		matches := re.FindStringSubmatch(url)
+		if len(matches) == 4 {
+			return matches[1], matches[2], matches[3], nil
+		}
+	}
+	return "", "", "", errors.New("failed to extract azure organization/project/repository")
+}
+
+func (p *Azure) open(remoteURL string, auth AuthToken) (requestSource, error) {
+	org, project, repo, err := getOrganizationProjectRepository(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+	common.Logger.Info(fmt.Sprintf("Organization: %s, Project: %s, Repo: %s\n", org, project, repo))
+
+	return &azureRepo{
+		provider: p,
+		auth:     auth,
+		org:      org,
+		project:  project,
+		repo:     repo,
+		client:   newClient(p.Options),
+	}, nil
+}
+
+// request - A REST request carrying the API version and the PAT, if any
+func (a *azureRepo) request() *resty.Request {
+	req := a.client.r().
+		SetHeader("Accept", "application/json").
+		SetQueryParam("api-version", "6.0")
+	if len(a.auth.AccessToken) > 0 {
+		// Personal Access Tokens are sent as the password of basic auth with an empty user
+		req.SetBasicAuth("", a.auth.AccessToken)
+	}
+	return req
+}
+
+func (a *azureRepo) requestText() string {
+	return "Pull Request"
+}
+
+func (a *azureRepo) match(c *object.Commit) ([]uint, error) {
+	if !strings.HasPrefix(c.Message, "Merged PR ") {
+		return nil, nil
+	}
+	pr, err := parseAzurePRNumber(strings.Split(c.Message, "\n")[0])
+	if err != nil {
+		return nil, err
+	}
+	return []uint{pr}, nil
+}
+
+func (a *azureRepo) fetch(number uint) (*Request, error) {
+	// curl -su ":${AZURE_DEVOPS_TOKEN}" "https://dev.azure.com/{organization}/{project}/_apis/git/repositories/{repository}/pullrequests/5?api-version=6.0" | jq -r '.description'
+	uri := fmt.Sprintf("%s/%s/%s/_apis/git/repositories/%s/pullrequests/%d", hostURL(a.provider.Host), a.org, a.project, a.repo, number)
+	common.Logger.Debug(fmt.Sprintf("PR URI: %s", uri))
+	var pullRequest AzurePullRequest
+	if err := a.client.get(a.request(), uri, cacheKey(a.provider.Provider, a.provider.Host, fmt.Sprintf("%s/%s/%s", a.org, a.project, a.repo), number), &pullRequest); err != nil {
+		return nil, err
+	}
+
+	labels := []string{}
+	for _, l := range pullRequest.Labels {
+		labels = append(labels, l.Name)
+	}
+
+	return &Request{
+		Number:   number,
+		Title:    pullRequest.Title,
+		Body:     pullRequest.Description,
+		URL:      fmt.Sprintf("%s/pullrequest/%d", pullRequest.Repository.WebURL, number),
+		Labels:   labels,
+		Author:   pullRequest.CreatedBy.UniqueName,
+		MergedAt: timeOf(pullRequest.ClosedDate),
+		MergeSHA: pullRequest.LastMergeCommit.CommitID,
+	}, nil
+}
+
+// GetChangelog - Collect the changelog from the PR descriptions since sinceTag
+func (p *Azure) GetChangelog(src string, sinceTag string, release string, auth AuthToken) (*common.Changelog, error) {
+	return collect(p, p.Options, src, sinceTag, release, auth)
+}
+
+// GetChangeLogFromPRMR - Get the changelog details from the PR/MR description
+func (p *Azure) GetChangeLogFromPRMR(src string, sinceTag string, release string, auth AuthToken, fileName string) (string, error) {
+	return render(p, p.Options, src, sinceTag, release, auth, fileName)
+}
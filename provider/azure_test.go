@@ -0,0 +1,64 @@
+package provider_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	clprovider "changelog-pr/provider"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Azure", func() {
+
+	var (
+		server *testServer
+	)
+
+	BeforeEach(func() {
+		server = newTestServer(func(w http.ResponseWriter, r *http.Request) {
+			user, pass, _ := r.BasicAuth()
+			if user != "" || pass != "pat" || r.URL.Query().Get("api-version") == "" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"description": "## Changelog Inclusions\n\n### Changes\n\n- Azure change\n", "repository": {"webUrl": "https://dev.azure.com/org/project/_git/repo"}}`)
+		})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	table.DescribeTable("collects the changelog using the origin remote",
+		func(remote string) {
+			repo := newTestRepo(remote,
+				testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+				testCommit{Message: "Merged PR 1234: Change a thing\n\nRelated work items: #1"},
+			)
+			defer os.RemoveAll(repo)
+
+			gp := server.provider(clprovider.AZURE, clprovider.Options{})
+
+			out, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{AccessToken: "pat"}, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(server.Paths()).To(Equal([]string{"/org/project/_apis/git/repositories/repo/pullrequests/1234"}))
+			Expect(out).To(Equal(`## v0.2.0
+
+### Changes
+
+#### [Pull Request #1234](https://dev.azure.com/org/project/_git/repo/pullrequest/1234)
+
+- Azure change
+
+`))
+		},
+		table.Entry("https", "https://dev.azure.com/org/project/_git/repo"),
+		table.Entry("https with user", "https://org@dev.azure.com/org/project/_git/repo"),
+		table.Entry("ssh", "git@ssh.dev.azure.com:v3/org/project/repo"),
+	)
+
+})
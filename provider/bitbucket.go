@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"changelog-pr/common"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-resty/resty/v2"
+)
+
+// Bitbucket - Structure to hold stuff
+type Bitbucket struct {
+	Provider string
+	Host     string
+	Options  Options
+}
+
+type BBPullRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Links       struct {
+		HTML struct {
+			HREF string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+	Author struct {
+		Nickname string `json:"nickname"`
+	} `json:"author"`
+	MergeCommit *struct {
+		Hash string `json:"hash"`
+	} `json:"merge_commit"`
+}
+
+// bitbucketRepo - A Bitbucket Cloud repository, named by its workspace and slug
+type bitbucketRepo struct {
+	provider  *Bitbucket
+	auth      AuthToken
+	workspace string
+	repo      string
+	client    *client
+}
+
+func parseBitbucketPRNumber(msg string) (uint, error) {
+	matches := numBitbucketRegex.FindAllStringSubmatch(msg, 1)
+	if len(matches) == 0 || len(matches[0]) < 2 {
+		return 0, fmt.Errorf("could not find PR number in commit message")
+	}
+	u64, err := strconv.ParseUint(matches[0][1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse PR number %q from commit message: %v", matches[0][1], err)
+	}
+	return uint(u64), nil
+}
+
+// apiURL - The REST API root for the configured host, Bitbucket Cloud serves the
+// API on its api. subdomain
+func (p *Bitbucket) apiURL() string {
+	if len(p.Host) == 0 || p.Host == "bitbucket.org" {
+		return "https://api.bitbucket.org/2.0"
+	}
+	return fmt.Sprintf("%s/2.0", hostURL(p.Host))
+}
+
+func (p *Bitbucket) open(remoteURL string, auth AuthToken) (requestSource, error) {
+	workspace, repo, err := getUserRepository(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+	common.Logger.Info(fmt.Sprintf("Workspace: %s, Repo: %s\n", workspace, repo))
+
+	return &bitbucketRepo{
+		provider:  p,
+		auth:      auth,
+		workspace: workspace,
+		repo:      repo,
+		client:    newClient(p.Options),
+	}, nil
+}
+
+// request - A REST request carrying the Bitbucket credentials, if any
+func (b *bitbucketRepo) request() *resty.Request {
+	req := b.client.r().SetHeader("Accept", "application/json")
+	if len(b.auth.AccessToken) > 0 {
+		// An app password is supplied as "username:app_password", anything
+		// else is treated as a repository/workspace access token
+		if creds := strings.SplitN(b.auth.AccessToken, ":", 2); len(creds) == 2 {
+			req.SetBasicAuth(creds[0], creds[1])
+		} else {
+			req.SetAuthToken(b.auth.AccessToken)
+		}
+	}
+	return req
+}
+
+func (b *bitbucketRepo) requestText() string {
+	return "Pull Request"
+}
+
+func (b *bitbucketRepo) match(c *object.Commit) ([]uint, error) {
+	if !strings.HasPrefix(c.Message, "Merged in ") {
+		return nil, nil
+	}
+	pr, err := parseBitbucketPRNumber(strings.Split(c.Message, "\n")[0])
+	if err != nil {
+		return nil, err
+	}
+	return []uint{pr}, nil
+}
+
+func (b *bitbucketRepo) fetch(number uint) (*Request, error) {
+	// curl -s https://api.bitbucket.org/2.0/repositories/{workspace}/{repo_slug}/pullrequests/5 | jq -r '.description'
+	uri := fmt.Sprintf("%s/repositories/%s/%s/pullrequests/%d", b.provider.apiURL(), b.workspace, b.repo, number)
+	common.Logger.Debug(fmt.Sprintf("PR URI: %s", uri))
+	var pullRequest BBPullRequest
+	if err := b.client.get(b.request(), uri, cacheKey(b.provider.Provider, b.provider.Host, fmt.Sprintf("%s/%s", b.workspace, b.repo), number), &pullRequest); err != nil {
+		return nil, err
+	}
+
+	// Bitbucket does not report when a pull request was merged, nor the full hash
+	// of the merge commit, so both are taken from the merge commit in the history
+	return &Request{
+		Number: number,
+		Title:  pullRequest.Title,
+		Body:   pullRequest.Description,
+		URL:    pullRequest.Links.HTML.HREF,
+		Author: pullRequest.Author.Nickname,
+	}, nil
+}
+
+// GetChangelog - Collect the changelog from the PR descriptions since sinceTag
+func (p *Bitbucket) GetChangelog(src string, sinceTag string, release string, auth AuthToken) (*common.Changelog, error) {
+	return collect(p, p.Options, src, sinceTag, release, auth)
+}
+
+// GetChangeLogFromPRMR - Get the changelog details from the PR/MR description
+func (p *Bitbucket) GetChangeLogFromPRMR(src string, sinceTag string, release string, auth AuthToken, fileName string) (string, error) {
+	return render(p, p.Options, src, sinceTag, release, auth, fileName)
+}
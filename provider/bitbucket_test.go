@@ -0,0 +1,89 @@
+package provider_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	clprovider "changelog-pr/provider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bitbucket", func() {
+
+	var (
+		server *testServer
+		repo   string
+	)
+
+	BeforeEach(func() {
+		server = newTestServer(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/2.0/repositories/workspace/repo/pullrequests/5":
+				fmt.Fprint(w, `{"description": "## Changelog Inclusions\n\n### Additions\n\n- Bitbucket addition\n", "links": {"html": {"href": "https://bitbucket.org/workspace/repo/pull-requests/5"}}}`)
+			case "/2.0/repositories/workspace/repo/pullrequests/6":
+				fmt.Fprint(w, `{"description": "## Changelog Inclusions\n\n### Fixes\n\n- Bitbucket fix\n", "links": {"html": {"href": "https://bitbucket.org/workspace/repo/pull-requests/6"}}}`)
+			default:
+				http.NotFound(w, r)
+			}
+		})
+		repo = newTestRepo("git@bitbucket.org:workspace/repo.git",
+			testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+			testCommit{Message: "Merged in feature/thing (pull request #5)\n\nAdd a thing\n"},
+			testCommit{Message: "Direct commit citing (pull request #9)\n"},
+			testCommit{Message: "Merged in bugfix/thing (pull request #6)\n\nFix a thing\n"},
+		)
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(repo)
+	})
+
+	It("collects the changelog from the pull requests of \"Merged in\" commits", func() {
+		gp := server.provider(clprovider.BITBUCKET, clprovider.Options{})
+
+		out, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{AccessToken: "secret"}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal(`## v0.2.0
+
+### Additions
+
+#### [Pull Request #5](https://bitbucket.org/workspace/repo/pull-requests/5)
+
+- Bitbucket addition
+
+
+### Bug Fixes
+
+#### [Pull Request #6](https://bitbucket.org/workspace/repo/pull-requests/6)
+
+- Bitbucket fix
+
+`))
+		Expect(server.Paths()).To(ConsistOf(
+			"/2.0/repositories/workspace/repo/pullrequests/5",
+			"/2.0/repositories/workspace/repo/pullrequests/6",
+		))
+	})
+
+	It("sends an access token as a bearer token", func() {
+		gp := server.provider(clprovider.BITBUCKET, clprovider.Options{})
+
+		_, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{AccessToken: "secret"}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(server.Auth()).To(ConsistOf("Bearer secret", "Bearer secret"))
+	})
+
+	It("sends a username:app_password as basic auth", func() {
+		gp := server.provider(clprovider.BITBUCKET, clprovider.Options{})
+
+		_, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{AccessToken: "user:app-password"}, "")
+		Expect(err).NotTo(HaveOccurred())
+		// base64 of "user:app-password"
+		Expect(server.Auth()).To(ConsistOf("Basic dXNlcjphcHAtcGFzc3dvcmQ=", "Basic dXNlcjphcHAtcGFzc3dvcmQ="))
+	})
+
+})
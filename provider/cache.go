@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var unsafePathRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Cache - PR/MR API responses kept on disk between runs, they are revalidated
+// with conditional requests so an unchanged PR/MR costs no rate limit
+type Cache struct {
+	Dir string
+}
+
+// cacheEntry - A saved response body and the ETag it is revalidated with
+type cacheEntry struct {
+	ETag string          `json:"etag"`
+	Body json.RawMessage `json:"body"`
+}
+
+// NewCache - A cache stored below dir
+func NewCache(dir string) *Cache {
+	return &Cache{Dir: dir}
+}
+
+// cacheKey - The cache key of a PR/MR, e.g. github/github.com/Maahsome/changelog-pr/16.json
+func cacheKey(provider string, host string, repo string, number uint) string {
+	parts := []string{provider, host}
+	parts = append(parts, strings.Split(repo, "/")...)
+	for i, p := range parts {
+		parts[i] = unsafePathRegex.ReplaceAllString(p, "_")
+	}
+	return filepath.Join(append(parts, fmt.Sprintf("%d.json", number))...)
+}
+
+func (c *Cache) load(key string) (*cacheEntry, error) {
+	data, err := ioutil.ReadFile(filepath.Join(c.Dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (c *Cache) save(key string, entry *cacheEntry) error {
+	path := filepath.Join(c.Dir, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// Clear - Remove every cached response
+func (c *Cache) Clear() error {
+	return os.RemoveAll(c.Dir)
+}
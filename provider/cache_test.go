@@ -0,0 +1,84 @@
+package provider_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	clprovider "changelog-pr/provider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cache", func() {
+
+	var (
+		server   *testServer
+		repo     string
+		cacheDir string
+		statuses []int
+	)
+
+	BeforeEach(func() {
+		statuses = []int{}
+		server = newTestServer(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				statuses = append(statuses, http.StatusNotModified)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			statuses = append(statuses, http.StatusOK)
+			w.Header().Set("ETag", `"v1"`)
+			fmt.Fprint(w, `{"body": "## Changelog Inclusions\n\n### Additions\n\n- Cached addition\n", "_links": {"html": {"href": "https://example.com/pull/5"}}}`)
+		})
+		repo = newTestRepo("git@example.com:owner/repo.git",
+			testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+			testCommit{Message: "Merge pull request #5 from owner/feature"},
+		)
+		var err error
+		cacheDir, err = ioutil.TempDir("", "changelog-pr-cache")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(repo)
+		os.RemoveAll(cacheDir)
+	})
+
+	It("revalidates cached descriptions with the ETag", func() {
+		cache := clprovider.NewCache(cacheDir)
+		gp := server.provider(clprovider.GITHUB, clprovider.Options{Cache: cache})
+
+		first, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+		Expect(err).NotTo(HaveOccurred())
+		second, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(statuses).To(Equal([]int{http.StatusOK, http.StatusNotModified}))
+		Expect(second).To(Equal(first))
+		Expect(second).To(ContainSubstring("- Cached addition"))
+
+		matches, err := filepath.Glob(filepath.Join(cacheDir, "github", "*", "owner", "repo", "5.json"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(matches).To(HaveLen(1))
+
+		Expect(cache.Clear()).To(Succeed())
+		_, err = os.Stat(cacheDir)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("does not send conditional requests when caching is disabled", func() {
+		gp := server.provider(clprovider.GITHUB, clprovider.Options{})
+
+		for i := 0; i < 2; i++ {
+			_, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(statuses).To(Equal([]int{http.StatusOK, http.StatusOK}))
+	})
+
+})
@@ -0,0 +1,96 @@
+package provider_test
+
+import (
+	"os"
+
+	"changelog-pr/common"
+	clprovider "changelog-pr/provider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Categories", func() {
+
+	var repo string
+
+	BeforeEach(func() {
+		common.NewLogger("Warn", "")
+		repo = newTestRepo("",
+			testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+			testCommit{Message: "Harden the login\n\n## Changelog Inclusions\n\n### Security\n\n- Rate limit logins\n\n### Fixes\n\n- Fixed the login form\n"},
+			testCommit{Message: "Speed up\n\n## Changelog Inclusions\n\n### Performance\n\n- Faster startup\n\n### Additions\n\n- Added a cache\n"},
+		)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(repo)
+	})
+
+	It("collects and renders the configured categories in order", func() {
+		categories, err := common.MergeCategories([]common.Category{
+			{Name: "security", Heading: "Security", Title: "Security Fixes", Order: 5},
+			{Name: "performance", Heading: "Performance", Order: 55},
+			{Name: common.BUGFIXES, Title: "Fixed"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		p, err := clprovider.GetProvider(clprovider.LOCAL, "", clprovider.Options{Categories: categories})
+		Expect(err).NotTo(HaveOccurred())
+
+		out, err := p.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(MatchRegexp(`^## v0\.2\.0
+
+### Security Fixes
+
+#### Commit #[0-9a-f]{7}
+
+- Rate limit logins
+
+
+### Additions
+
+#### Commit #[0-9a-f]{7}
+
+- Added a cache
+
+
+### Fixed
+
+#### Commit #[0-9a-f]{7}
+
+- Fixed the login form
+
+
+### Performance
+
+#### Commit #[0-9a-f]{7}
+
+- Faster startup
+
+$`))
+	})
+
+	It("requires a name for every configured category", func() {
+		_, err := common.MergeCategories([]common.Category{{Heading: "Security"}})
+		Expect(err).To(MatchError(ContainSubstring("needs a name")))
+	})
+
+	It("removes a disabled built in category", func() {
+		categories, err := common.MergeCategories([]common.Category{{Name: "Deprecations", Disabled: true}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(categories).To(HaveLen(len(common.DefaultCategories) - 1))
+		Expect(common.PRTemplate(categories)).NotTo(ContainSubstring("### Deprecated"))
+
+		_, err = common.MergeCategories([]common.Category{{Name: "security", Disabled: true}})
+		Expect(err).To(MatchError(`there is no "security" changelog category to disable`))
+	})
+
+	It("adds a section for every category to the PR template", func() {
+		categories, err := common.MergeCategories([]common.Category{{Name: "security", Heading: "Security", Order: 15}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(common.PRTemplate(categories)).To(HaveSuffix("### Additions\n\n### Security\n\n### Changes\n\n### Removed\n\n### Deprecated\n\n### Fixes\n\n### Breaking Changes"))
+	})
+
+})
@@ -0,0 +1,216 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"changelog-pr/common"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	defaultRetryWait    = time.Second
+	defaultRetryMaxWait = 15 * time.Minute
+)
+
+// client - The HTTP client shared by the providers, it waits out rate limits and
+// retries failed requests with an exponential backoff
+type client struct {
+	restClient   *resty.Client
+	cache        *Cache
+	retries      int
+	retryWait    time.Duration
+	retryMaxWait time.Duration
+}
+
+// newClient - Create a client using the retry settings of the options
+func newClient(o Options) *client {
+	c := &client{
+		restClient:   resty.New(),
+		cache:        o.Cache,
+		retries:      o.Retries,
+		retryWait:    o.RetryWait,
+		retryMaxWait: o.RetryMaxWait,
+	}
+	if c.retries < 0 {
+		c.retries = 0
+	}
+	if c.retryWait <= 0 {
+		c.retryWait = defaultRetryWait
+	}
+	if c.retryMaxWait <= 0 {
+		c.retryMaxWait = defaultRetryMaxWait
+	}
+	// Recorded and replayed runs bypass the cache so the fixtures hold complete responses
+	switch {
+	case len(o.Replay) > 0:
+		c.cache = nil
+		c.restClient.SetTransport(&replayTransport{dir: o.Replay})
+	case len(o.Record) > 0:
+		c.cache = nil
+		c.restClient.SetTransport(&recordTransport{dir: o.Record, next: http.DefaultTransport})
+	}
+	return c
+}
+
+// r - A new request to be passed to get
+func (c *client) r() *resty.Request {
+	return c.restClient.R()
+}
+
+// get - Perform a GET request, waiting and retrying while the provider rate limits
+// us or fails, and decode the JSON response into out.  A request that still fails
+// after the retries are exhausted is returned as an error.  When a cacheKey is given
+// and caching is enabled the response is saved, and revalidated on later runs
+func (c *client) get(req *resty.Request, uri string, cacheKey string, out interface{}) error {
+	var cached *cacheEntry
+	if c.cache != nil && len(cacheKey) > 0 {
+		entry, err := c.cache.load(cacheKey)
+		if err != nil {
+			common.Logger.WithError(err).Warn(fmt.Sprintf("Ignoring the unreadable cache entry %s", cacheKey))
+		}
+		if entry != nil && len(entry.ETag) > 0 {
+			cached = entry
+			req.SetHeader("If-None-Match", entry.ETag)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := req.Get(uri)
+		if err == nil {
+			common.Logger.Trace(fmt.Sprintf("Response from %s: %s", uri, resp.Body()))
+		}
+		if err == nil && resp.StatusCode() == http.StatusNotModified && cached != nil {
+			common.Logger.Debug(fmt.Sprintf("Using the cached response for %s", uri))
+			return decode(uri, cached.Body, out)
+		}
+		if err == nil && !resp.IsError() {
+			if c.cache != nil && len(cacheKey) > 0 && len(resp.Header().Get("ETag")) > 0 {
+				serr := c.cache.save(cacheKey, &cacheEntry{ETag: resp.Header().Get("ETag"), Body: resp.Body()})
+				if serr != nil {
+					common.Logger.WithError(serr).Warn(fmt.Sprintf("Failed to cache the response for %s", uri))
+				}
+			}
+			return decode(uri, resp.Body(), out)
+		}
+
+		wait, retry := c.retryAfter(resp, err, attempt)
+		if !retry || attempt >= c.retries {
+			if err != nil {
+				return fmt.Errorf("GET %s failed after %d attempt(s): %v", uri, attempt+1, err)
+			}
+			return &statusError{uri: uri, attempts: attempt + 1, code: resp.StatusCode(), status: resp.Status()}
+		}
+
+		if err != nil {
+			common.Logger.WithError(err).Warn(fmt.Sprintf("GET %s failed, retrying in %s", uri, wait))
+		} else {
+			common.Logger.Warn(fmt.Sprintf("GET %s returned %s, retrying in %s", uri, resp.Status(), wait))
+		}
+		time.Sleep(wait)
+	}
+}
+
+// statusError - A request the provider still answered with an error status after
+// the retries
+type statusError struct {
+	uri      string
+	attempts int
+	code     int
+	status   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("GET %s failed after %d attempt(s): %s", e.uri, e.attempts, e.status)
+}
+
+// isNotFound - Whether the provider answered a request with 404 Not Found
+func isNotFound(err error) bool {
+	var se *statusError
+	return errors.As(err, &se) && se.code == http.StatusNotFound
+}
+
+func decode(uri string, body []byte, out interface{}) error {
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("could not decode the response from %s: %v", uri, err)
+	}
+	return nil
+}
+
+// retryAfter - Decide whether a failed request is worth retrying and how long to
+// wait first.  Rate limited responses are waited out using the Retry-After header,
+// or the reset time of GitHub's X-RateLimit-* and GitLab's RateLimit-* headers
+func (c *client) retryAfter(resp *resty.Response, err error, attempt int) (time.Duration, bool) {
+	backoff := c.retryWait << uint(attempt)
+	if backoff <= 0 || backoff > c.retryMaxWait {
+		backoff = c.retryMaxWait
+	}
+
+	if errors.Is(err, errNoFixture) {
+		return 0, false
+	}
+	if err != nil {
+		// Connection failures and timeouts
+		return backoff, true
+	}
+
+	header := resp.Header()
+	status := resp.StatusCode()
+	rateLimited := status == http.StatusTooManyRequests ||
+		(status == http.StatusForbidden && (len(header.Get("Retry-After")) > 0 ||
+			header.Get("X-RateLimit-Remaining") == "0" ||
+			header.Get("RateLimit-Remaining") == "0"))
+
+	switch {
+	case rateLimited:
+		wait := backoff
+		if d, ok := parseRetryAfter(header.Get("Retry-After")); ok {
+			wait = d
+		} else if d, ok := untilReset(header.Get("X-RateLimit-Reset")); ok {
+			wait = d
+		} else if d, ok := untilReset(header.Get("RateLimit-Reset")); ok {
+			wait = d
+		}
+		if wait < c.retryWait {
+			wait = c.retryWait
+		}
+		if wait > c.retryMaxWait {
+			wait = c.retryMaxWait
+		}
+		return wait, true
+	case status >= http.StatusInternalServerError:
+		return backoff, true
+	}
+	return 0, false
+}
+
+// parseRetryAfter - Retry-After holds either a number of seconds or an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if len(value) == 0 {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		return time.Until(when), true
+	}
+	return 0, false
+}
+
+// untilReset - The time left until a rate limit reset given in UTC epoch seconds
+func untilReset(value string) (time.Duration, bool) {
+	if len(value) == 0 {
+		return 0, false
+	}
+	epoch, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Until(time.Unix(epoch, 0)), true
+}
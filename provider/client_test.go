@@ -0,0 +1,129 @@
+package provider_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"changelog-pr/common"
+	clprovider "changelog-pr/provider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeResponse - A canned failure served before the real response
+type fakeResponse struct {
+	status  int
+	headers map[string]string
+}
+
+var _ = Describe("Client", func() {
+
+	var (
+		server    *testServer
+		repo      string
+		mu        sync.Mutex
+		failures  []fakeResponse
+		opts      clprovider.Options
+		prPayload = `{"body": "## Changelog Inclusions\n\n### Fixes\n\n- Fixed\n", "description": "## Changelog Inclusions\n\n### Fixes\n\n- Fixed\n", "_links": {"html": {"href": "https://example.com/pull/1"}}, "web_url": "https://example.com/merge_requests/1"}`
+	)
+
+	BeforeEach(func() {
+		common.NewLogger("Error", "")
+		failures = []fakeResponse{}
+		opts = clprovider.Options{Retries: 3, RetryWait: time.Millisecond, RetryMaxWait: 10 * time.Millisecond}
+		server = newTestServer(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(failures) > 0 {
+				f := failures[0]
+				failures = failures[1:]
+				for k, v := range f.headers {
+					w.Header().Set(k, v)
+				}
+				http.Error(w, http.StatusText(f.status), f.status)
+				return
+			}
+			fmt.Fprint(w, prPayload)
+		})
+		repo = newTestRepo("git@example.com:owner/repo.git",
+			testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+			testCommit{Message: "Merge pull request #1 from owner/fix\n\nSee merge request owner/repo!1"},
+		)
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(repo)
+	})
+
+	now := func() string {
+		return strconv.FormatInt(time.Now().Unix(), 10)
+	}
+
+	It("waits out GitHub primary and secondary rate limits", func() {
+		failures = []fakeResponse{
+			{status: http.StatusForbidden, headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": now()}},
+			{status: http.StatusForbidden, headers: map[string]string{"Retry-After": "0"}},
+			{status: http.StatusTooManyRequests},
+		}
+		gp := server.provider(clprovider.GITHUB, opts)
+
+		changeLog, err := gp.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(server.Hits()).To(Equal(4))
+		Expect(changeLog.Entries(common.BUGFIXES)).To(HaveLen(1))
+	})
+
+	It("waits out GitLab rate limits", func() {
+		failures = []fakeResponse{
+			{status: http.StatusTooManyRequests, headers: map[string]string{"RateLimit-Remaining": "0", "RateLimit-Reset": now()}},
+		}
+		gp := server.provider(clprovider.GITLAB, opts)
+
+		changeLog, err := gp.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(server.Hits()).To(Equal(2))
+		Expect(changeLog.Entries(common.BUGFIXES)).To(HaveLen(1))
+	})
+
+	It("retries server errors with a backoff", func() {
+		failures = []fakeResponse{
+			{status: http.StatusBadGateway},
+			{status: http.StatusServiceUnavailable},
+		}
+		gp := server.provider(clprovider.GITHUB, opts)
+
+		_, err := gp.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(server.Hits()).To(Equal(3))
+	})
+
+	It("fails the run when the rate limit outlasts the retries", func() {
+		for i := 0; i < 10; i++ {
+			failures = append(failures, fakeResponse{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "0"}})
+		}
+		gp := server.provider(clprovider.GITHUB, opts)
+
+		_, err := gp.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).To(MatchError(ContainSubstring("failed after 4 attempt(s): 429")))
+		Expect(server.Hits()).To(Equal(4))
+
+		_, err = gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+		Expect(err).To(MatchError(ContainSubstring("failed generation of changelog")))
+	})
+
+	It("does not retry a forbidden response that is not rate limited", func() {
+		failures = []fakeResponse{{status: http.StatusForbidden}}
+		gp := server.provider(clprovider.GITHUB, opts)
+
+		_, err := gp.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).To(MatchError(ContainSubstring("failed after 1 attempt(s): 403")))
+		Expect(server.Hits()).To(Equal(1))
+	})
+
+})
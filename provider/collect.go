@@ -0,0 +1,310 @@
+package provider
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"changelog-pr/common"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Request - A pull/merge request as fetched from a provider API
+type Request struct {
+	Number uint
+	Title  string
+	Body   string
+	URL    string
+	// Labels are the names of the labels on the request, Bitbucket has none
+	Labels []string
+	// Author is the login of the author
+	Author string
+	// MergedAt and MergeSHA default to the commit that referenced the request
+	MergedAt time.Time
+	MergeSHA string
+	// ref names a request that has no number, e.g. the short hash of a commit
+	ref string
+}
+
+// id - How the request is referred to in the changelog, e.g. "12" for #12
+func (r *Request) id() string {
+	if len(r.ref) > 0 {
+		return r.ref
+	}
+	return fmt.Sprintf("%d", r.Number)
+}
+
+// timeOf - The time t points at, zero when t is nil
+func timeOf(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+// entry - The request as recorded on its changelog entries
+func (r *Request) entry(kind string) common.Request {
+	return common.Request{
+		Kind:     kind,
+		Number:   r.id(),
+		Title:    strings.TrimSpace(r.Title),
+		URL:      r.URL,
+		Author:   r.Author,
+		MergedAt: r.MergedAt,
+		Labels:   r.Labels,
+		MergeSHA: r.MergeSHA,
+	}
+}
+
+// opener - Implemented by the API backed providers, it reads the owner and
+// repository from the origin remote URL
+type opener interface {
+	open(remoteURL string, auth AuthToken) (requestSource, error)
+}
+
+// requestSource - What a provider supplies to the shared history walk: how its
+// commits reference requests, and how to fetch a request from its API
+type requestSource interface {
+	// requestText - How a request is named in the changelog, e.g. "Pull Request"
+	requestText() string
+	// match - The request numbers a commit refers to, nil when there are none
+	match(c *object.Commit) ([]uint, error)
+	// fetch - Fetch a request from the provider API, nil when the number turned out
+	// not to be a request
+	fetch(number uint) (*Request, error)
+}
+
+// inPool - Run job for every index below n using a bounded pool of workers
+func inPool(n int, workers int, job func(i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				job(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// findRequests - The unique request numbers referenced by commits, in commit order,
+// and the newest commit referencing each of them.  The commits are matched using a
+// bounded pool of workers, as a lookup strategy asks the provider API per commit
+func findRequests(source requestSource, commits []*object.Commit, workers int) ([]uint, map[uint]*object.Commit) {
+	matches := make([][]uint, len(commits))
+	errs := make([]error, len(commits))
+	inPool(len(commits), workers, func(i int) {
+		common.Logger.Trace(commits[i].Message)
+		matches[i], errs[i] = source.match(commits[i])
+	})
+
+	numbers := []uint{}
+	seen := map[uint]*object.Commit{}
+	for i, c := range commits {
+		if errs[i] != nil {
+			common.Logger.WithError(errs[i]).Error(fmt.Sprintf("Bad %s lookup for %s", source.requestText(), c.Hash))
+			continue
+		}
+		for _, n := range matches[i] {
+			if seen[n] != nil {
+				continue
+			}
+			seen[n] = c
+			numbers = append(numbers, n)
+			common.Logger.Info(fmt.Sprintf("%s %s\n", c.ID(), strings.Split(c.Message, "\n")[0]))
+		}
+	}
+	return numbers, seen
+}
+
+// fetchRequests - Fetch requests using a bounded pool of workers, the results keep
+// the order of numbers, leaving out numbers that are not requests, and every
+// failed fetch is reported in the returned error
+func fetchRequests(source requestSource, numbers []uint, workers int) ([]*Request, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	common.Logger.Info(fmt.Sprintf("Fetching %d %ss using %d workers", len(numbers), source.requestText(), workers))
+
+	requests := make([]*Request, len(numbers))
+	errs := make([]error, len(numbers))
+	inPool(len(numbers), workers, func(i int) {
+		requests[i], errs[i] = source.fetch(numbers[i])
+	})
+
+	failures := []string{}
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s #%d: %v", source.requestText(), numbers[i], err))
+		}
+	}
+	if len(failures) > 0 {
+		return nil, fmt.Errorf("failed to fetch %d of %d %ss:\n  %s", len(failures), len(numbers), source.requestText(), strings.Join(failures, "\n  "))
+	}
+
+	found := []*Request{}
+	for _, request := range requests {
+		if request != nil {
+			found = append(found, request)
+		}
+	}
+	return found, nil
+}
+
+// collect - Build the changelog from the requests merged since sinceTag
+func collect(p opener, o Options, src string, sinceTag string, release string, auth AuthToken) (*common.Changelog, error) {
+	h, err := OpenHistory(src)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteURL, err := h.RemoteURL("origin")
+	if err != nil {
+		return nil, err
+	}
+	common.Logger.Debug(fmt.Sprintf("Remote URL: %s", remoteURL))
+
+	source, err := p.open(remoteURL, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	commitRange, err := h.Between(sinceTag, "")
+	if err != nil {
+		return nil, err
+	}
+
+	changeLog := common.NewChangelog(release, o.Categories)
+	changeLog.Since = commitRange.From.Name().Short()
+
+	numbers, merges := findRequests(source, commitRange.Commits, o.Concurrency)
+	requests, err := fetchRequests(source, numbers, o.Concurrency)
+	if err != nil {
+		return nil, err
+	}
+	for _, request := range requests {
+		merge := merges[request.Number]
+		if len(request.MergeSHA) == 0 {
+			request.MergeSHA = merge.Hash.String()
+		}
+		if request.MergedAt.IsZero() {
+			request.MergedAt = merge.Committer.When
+		}
+	}
+
+	if err := o.collectRequests(changeLog, requests, source.requestText()); err != nil {
+		return nil, err
+	}
+
+	return changeLog, nil
+}
+
+// collectRequests - Parse the requests into the changelog, applying the label
+// rules and the title fallback to requests without changelog entries.  The
+// requests that fell back to their title are listed in a warning
+func (o Options) collectRequests(cl *common.Changelog, requests []*Request, requestText string) error {
+	fellBack := []string{}
+	fallbackCategory := o.FallbackCategory
+	if len(fallbackCategory) == 0 {
+		fallbackCategory = common.UncategorizedCategory.Name
+	}
+
+	for _, request := range requests {
+		if label, ok := o.Labels.skip(request.Labels); ok {
+			common.Logger.Info(fmt.Sprintf("Skipping %s #%s, it is labelled %s", requestText, request.id(), label))
+			continue
+		}
+
+		entry := request.entry(requestText)
+		entries := cl.Count()
+		if err := o.parse(cl, entry, request.Body); err != nil {
+			return err
+		}
+		if cl.Count() > entries || len(strings.TrimSpace(request.Title)) == 0 {
+			continue
+		}
+
+		if category, ok := o.Labels.category(request.Labels); ok {
+			common.Logger.Info(fmt.Sprintf("Filing the title of %s #%s under %s by its labels", requestText, request.id(), category))
+			if err := cl.AddTitle(category, entry); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if o.Fallback == TITLE {
+			if strings.EqualFold(fallbackCategory, common.UncategorizedCategory.Name) {
+				cl.AddSection(common.UncategorizedCategory)
+			}
+			if err := cl.AddTitle(fallbackCategory, entry); err != nil {
+				return err
+			}
+			fellBack = append(fellBack, fmt.Sprintf("%s #%s: %s", requestText, request.id(), strings.TrimSpace(request.Title)))
+		}
+	}
+
+	if len(fellBack) > 0 {
+		common.Logger.Warn(fmt.Sprintf("%d %ss had no changelog entries, their titles were filed under %s:\n  %s", len(fellBack), requestText, cl.Section(fallbackCategory).Title, strings.Join(fellBack, "\n  ")))
+	}
+	return nil
+}
+
+// parse - Read the changelog entries of a request with the configured parser, the
+// Conventional Commits parser reads the title followed by the body for footers
+func (o Options) parse(cl *common.Changelog, request common.Request, body string) error {
+	switch strings.ToLower(o.Parser) {
+	case "", MARKDOWN:
+		return common.ParseMarkdown(body, request, cl, o.Boilerplate)
+	case CONVENTIONAL:
+		message := body
+		if len(request.Title) > 0 {
+			message = fmt.Sprintf("%s\n\n%s", request.Title, body)
+		}
+		return common.ParseConventional(message, request, cl, o.ConventionalTypes)
+	}
+	return fmt.Errorf("unsupported parser %q", o.Parser)
+}
+
+// render - Render the changelog of a provider with the configured template, or save it to fileName
+func render(p Provider, o Options, src string, sinceTag string, release string, auth AuthToken, fileName string) (string, error) {
+	changeLog, err := p.GetChangelog(src, sinceTag, release, auth)
+	if err != nil {
+		return "", fmt.Errorf("failed generation of changelog: %v", err)
+	}
+
+	data, err := changeLog.Format(o.Format, o.Template)
+	if err != nil {
+		return "", fmt.Errorf("failed generation of changelog: %v", err)
+	}
+
+	if len(fileName) > 0 {
+		if len(o.Format) > 0 && o.Format != common.MARKDOWN {
+			// structured output describes a single release, it replaces the file
+			err = ioutil.WriteFile(fileName, data, 0644)
+		} else if o.KeepAChangelog != nil {
+			err = o.KeepAChangelog.WriteFile(fileName, changeLog, o.Template)
+		} else {
+			err = changeLog.WriteFile(fileName, o.Template, o.OnExisting)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to write to the output file: %v", err)
+		}
+		return "Changelog data has been saved.", nil
+	}
+
+	return string(data), nil
+}
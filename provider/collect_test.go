@@ -0,0 +1,148 @@
+package provider_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"changelog-pr/common"
+	clprovider "changelog-pr/provider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Collect", func() {
+
+	var (
+		server          *testServer
+		repo            string
+		inFlight        int32
+		maxSeen         int32
+		failing         map[string]bool
+		numbers         map[string]string
+		lookups         int32
+		lookupsInFlight int32
+		delay           time.Duration
+	)
+
+	BeforeEach(func() {
+		inFlight, maxSeen = 0, 0
+		failing = map[string]bool{}
+		numbers = map[string]string{}
+		lookups, lookupsInFlight = 0, 0
+		delay = 0
+		server = newTestServer(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				seen := atomic.LoadInt32(&maxSeen)
+				if n <= seen || atomic.CompareAndSwapInt32(&maxSeen, seen, n) {
+					break
+				}
+			}
+			time.Sleep(delay)
+
+			if strings.HasSuffix(r.URL.Path, "/pulls") {
+				// /api/v3/repos/owner/repo/commits/{sha}/pulls
+				atomic.AddInt32(&lookups, 1)
+				// the lookups are all done before the first fetch
+				for {
+					seen := atomic.LoadInt32(&lookupsInFlight)
+					if n <= seen || atomic.CompareAndSwapInt32(&lookupsInFlight, seen, n) {
+						break
+					}
+				}
+				sha := strings.Split(r.URL.Path, "/")[7]
+				fmt.Fprintf(w, `[{"number": %s, "merged_at": "2021-03-01T00:00:00Z"}]`, numbers[sha])
+				return
+			}
+			pr := strings.TrimPrefix(r.URL.Path, "/api/v3/repos/owner/repo/pulls/")
+			if failing[pr] {
+				http.Error(w, "boom", http.StatusInternalServerError)
+				return
+			}
+			fmt.Fprintf(w, `{"body": "## Changelog Inclusions\n\n### Changes\n\n- Change %s\n", "_links": {"html": {"href": "https://github.example.internal/owner/repo/pull/%s"}}}`, pr, pr)
+		})
+
+		commits := []testCommit{{Message: "Initial commit", Tag: "v0.1.0"}}
+		for i := 1; i <= 8; i++ {
+			commits = append(commits, testCommit{Message: fmt.Sprintf("Merge pull request #%d from owner/feature-%d", i, i)})
+		}
+		repo = newTestRepo("git@github.example.internal:owner/repo.git", commits...)
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(repo)
+	})
+
+	It("fetches with bounded workers and keeps commit order", func() {
+		// slow responses make the workers overlap
+		delay = 20 * time.Millisecond
+		gp := server.provider(clprovider.GITHUB, clprovider.Options{Concurrency: 3})
+
+		changeLog, err := gp.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&maxSeen)).To(BeNumerically(">", 1))
+		Expect(atomic.LoadInt32(&maxSeen)).To(BeNumerically("<=", 3))
+
+		descriptions := []string{}
+		for _, e := range changeLog.Entries(common.CHANGES) {
+			descriptions = append(descriptions, strings.TrimSpace(e.Description))
+		}
+		Expect(descriptions).To(Equal([]string{
+			"- Change 8", "- Change 7", "- Change 6", "- Change 5",
+			"- Change 4", "- Change 3", "- Change 2", "- Change 1",
+		}))
+	})
+
+	It("looks up commits with bounded workers and keeps commit order", func() {
+		h, err := clprovider.OpenHistory(repo)
+		Expect(err).NotTo(HaveOccurred())
+		commitRange, err := h.Between("v0.1.0", "")
+		Expect(err).NotTo(HaveOccurred())
+		for _, c := range commitRange.Commits {
+			var n int
+			_, err := fmt.Sscanf(c.Message, "Merge pull request #%d", &n)
+			Expect(err).NotTo(HaveOccurred())
+			numbers[c.Hash.String()] = fmt.Sprintf("%d", n)
+		}
+
+		delay = 20 * time.Millisecond
+		gp := server.provider(clprovider.GITHUB, clprovider.Options{
+			Concurrency: 3,
+			Strategies:  map[string][]string{"default": {"lookup"}},
+		})
+
+		changeLog, err := gp.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&lookups)).To(Equal(int32(8)))
+		Expect(atomic.LoadInt32(&lookupsInFlight)).To(BeNumerically(">", 1))
+		Expect(atomic.LoadInt32(&maxSeen)).To(BeNumerically("<=", 3))
+
+		descriptions := []string{}
+		for _, e := range changeLog.Entries(common.CHANGES) {
+			descriptions = append(descriptions, strings.TrimSpace(e.Description))
+		}
+		Expect(descriptions).To(Equal([]string{
+			"- Change 8", "- Change 7", "- Change 6", "- Change 5",
+			"- Change 4", "- Change 3", "- Change 2", "- Change 1",
+		}))
+	})
+
+	It("reports every request that could not be fetched", func() {
+		failing["3"] = true
+		failing["6"] = true
+		gp := server.provider(clprovider.GITHUB, clprovider.Options{Concurrency: 4})
+
+		_, err := gp.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).To(MatchError(ContainSubstring("failed to fetch 2 of 8 Pull Requests")))
+		Expect(err.Error()).To(ContainSubstring("Pull Request #6"))
+		Expect(err.Error()).To(ContainSubstring("Pull Request #3"))
+	})
+
+})
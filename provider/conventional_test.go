@@ -0,0 +1,151 @@
+package provider_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"changelog-pr/common"
+	clprovider "changelog-pr/provider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Conventional Commits parser", func() {
+
+	var repo string
+
+	AfterEach(func() {
+		os.RemoveAll(repo)
+	})
+
+	Describe("commit messages", func() {
+
+		BeforeEach(func() {
+			repo = newTestRepo("",
+				testCommit{Message: "chore: initial commit", Tag: "v0.1.0"},
+				testCommit{Message: "feat(api): add the widget endpoint"},
+				testCommit{Message: "fix: stop the widget leaking\n\nCloses #4"},
+				testCommit{Message: "docs: describe the widget"},
+				testCommit{Message: "Not a conventional commit"},
+				testCommit{Message: "feat(api)!: rename the widget endpoint"},
+				testCommit{Message: "refactor: store widgets by id\n\nBREAKING CHANGE: widget names\nare no longer unique\n\nRefs: #7"},
+			)
+		})
+
+		It("maps the commit types onto the default categories", func() {
+			p, err := clprovider.GetProvider(clprovider.LOCAL, "", clprovider.Options{Parser: clprovider.CONVENTIONAL})
+			Expect(err).NotTo(HaveOccurred())
+
+			changeLog, err := p.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(changeLog.Entries(common.ADDITIONS)).To(HaveLen(1))
+			Expect(changeLog.Entries(common.ADDITIONS)[0].Description).To(Equal("- **api:** add the widget endpoint\n"))
+			Expect(changeLog.Entries(common.ADDITIONS)[0].Scope).To(Equal("api"))
+			Expect(changeLog.Entries(common.BUGFIXES)).To(HaveLen(1))
+			Expect(changeLog.Entries(common.BUGFIXES)[0].Description).To(Equal("- stop the widget leaking\n"))
+			Expect(changeLog.Entries(common.BUGFIXES)[0].Scope).To(BeEmpty())
+			Expect(changeLog.Entries(common.CHANGES)).To(BeEmpty())
+
+			Expect(changeLog.Entries(common.BREAKING)).To(HaveLen(2))
+			Expect(changeLog.Entries(common.BREAKING)[0].Description).To(Equal("- widget names are no longer unique\n"))
+			Expect(changeLog.Entries(common.BREAKING)[1].Description).To(Equal("- **api:** rename the widget endpoint\n"))
+			Expect(changeLog.Entries(common.BREAKING)[1].Scope).To(Equal("api"))
+		})
+
+		It("uses a configured type mapping", func() {
+			p, err := clprovider.GetProvider(clprovider.LOCAL, "", clprovider.Options{
+				Parser:            clprovider.CONVENTIONAL,
+				ConventionalTypes: map[string]string{"docs": common.CHANGES, "feat": common.CHANGES},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			changeLog, err := p.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changeLog.Entries(common.ADDITIONS)).To(BeEmpty())
+			Expect(changeLog.Entries(common.BUGFIXES)).To(BeEmpty())
+			Expect(changeLog.Entries(common.CHANGES)).To(HaveLen(2))
+			Expect(changeLog.Entries(common.CHANGES)[0].Description).To(Equal("- describe the widget\n"))
+			Expect(changeLog.Entries(common.CHANGES)[1].Description).To(Equal("- **api:** add the widget endpoint\n"))
+			Expect(changeLog.Entries(common.BREAKING)).To(HaveLen(2))
+		})
+
+		It("adds configured types to the default ones", func() {
+			p, err := clprovider.GetProvider(clprovider.LOCAL, "", clprovider.Options{
+				Parser:            clprovider.CONVENTIONAL,
+				ConventionalTypes: common.MergeConventionalTypes(map[string]string{"Docs": common.CHANGES, "refactor": ""}),
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			changeLog, err := p.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changeLog.Entries(common.ADDITIONS)).To(HaveLen(1))
+			Expect(changeLog.Entries(common.BUGFIXES)).To(HaveLen(1))
+			Expect(changeLog.Entries(common.CHANGES)).To(HaveLen(1))
+			Expect(changeLog.Entries(common.CHANGES)[0].Description).To(Equal("- describe the widget\n"))
+		})
+
+	})
+
+	Describe("pull request titles", func() {
+
+		var server *testServer
+
+		BeforeEach(func() {
+			server = newTestServer(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v1/repos/owner/repo/pulls/12":
+					fmt.Fprint(w, `{"title": "feat(ui): dark mode", "body": "Adds a dark mode", "html_url": "https://gitea.example.com/owner/repo/pulls/12"}`)
+				case "/api/v1/repos/owner/repo/pulls/13":
+					fmt.Fprint(w, `{"title": "fix: drop the legacy theme", "body": "BREAKING CHANGE: the legacy theme is gone", "html_url": "https://gitea.example.com/owner/repo/pulls/13"}`)
+				default:
+					http.NotFound(w, r)
+				}
+			})
+			repo = newTestRepo("https://gitea.example.com/owner/repo.git",
+				testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+				testCommit{Message: "Merge pull request 'feat(ui): dark mode' (#12) from feature/dark into main\n"},
+				testCommit{Message: "Merge pull request 'fix: drop the legacy theme' (#13) from legacy into main\n"},
+			)
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("reads the title and the description footers", func() {
+			gp := server.provider(clprovider.GITEA, clprovider.Options{Parser: clprovider.CONVENTIONAL})
+
+			out, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(Equal(`## v0.2.0
+
+### Additions
+
+#### [Pull Request #12](https://gitea.example.com/owner/repo/pulls/12)
+
+- **ui:** dark mode
+
+
+### Breaking Changes
+
+#### [Pull Request #13](https://gitea.example.com/owner/repo/pulls/13)
+
+- the legacy theme is gone
+
+`))
+		})
+
+	})
+
+	It("rejects an unknown parser", func() {
+		repo = newTestRepo("", testCommit{Message: "Initial commit", Tag: "v0.1.0"}, testCommit{Message: "feat: more"})
+		p, err := clprovider.GetProvider(clprovider.LOCAL, "", clprovider.Options{Parser: "asciidoc"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = p.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).To(MatchError(`unsupported parser "asciidoc"`))
+	})
+
+})
@@ -0,0 +1,106 @@
+package provider_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+
+	"changelog-pr/common"
+	clprovider "changelog-pr/provider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Title fallback", func() {
+
+	var (
+		server *testServer
+		repo   string
+		logged *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		logged = &bytes.Buffer{}
+		common.Logger.Out = logged
+		server = newTestServer(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v1/repos/owner/repo/pulls/1":
+				fmt.Fprint(w, `{"title": "Bump dependencies", "body": "Just a bump", "html_url": "https://gitea.example.com/owner/repo/pulls/1"}`)
+			case "/api/v1/repos/owner/repo/pulls/2":
+				fmt.Fprint(w, `{"title": "Add exports", "body": "## Changelog Inclusions\n\n### Additions\n\n- CSV exports\n", "html_url": "https://gitea.example.com/owner/repo/pulls/2"}`)
+			case "/api/v1/repos/owner/repo/pulls/3":
+				fmt.Fprint(w, `{"title": "Fix typo", "body": "", "html_url": "https://gitea.example.com/owner/repo/pulls/3"}`)
+			default:
+				http.NotFound(w, r)
+			}
+		})
+		repo = newTestRepo("https://gitea.example.com/owner/repo.git",
+			testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+			testCommit{Message: "Merge pull request 'Fix typo' (#3) from typo into main\n"},
+			testCommit{Message: "Merge pull request 'Add exports' (#2) from exports into main\n"},
+			testCommit{Message: "Merge pull request 'Bump dependencies' (#1) from deps into main\n"},
+		)
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(repo)
+	})
+
+	It("leaves out pull requests without entries by default", func() {
+		gp := server.provider(clprovider.GITEA, clprovider.Options{})
+		changeLog, err := gp.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changeLog.Count()).To(Equal(1))
+		Expect(logged.String()).To(BeEmpty())
+	})
+
+	It("files titles under Uncategorized and lists them", func() {
+		gp := server.provider(clprovider.GITEA, clprovider.Options{Fallback: clprovider.TITLE})
+
+		out, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal(`## v0.2.0
+
+### Additions
+
+#### [Pull Request #2](https://gitea.example.com/owner/repo/pulls/2)
+
+- CSV exports
+
+
+### Uncategorized
+
+#### [Pull Request #1](https://gitea.example.com/owner/repo/pulls/1)
+
+- Bump dependencies
+
+#### [Pull Request #3](https://gitea.example.com/owner/repo/pulls/3)
+
+- Fix typo
+
+`))
+		Expect(logged.String()).To(ContainSubstring(`2 Pull Requests had no changelog entries, their titles were filed under Uncategorized:\n  Pull Request #1: Bump dependencies\n  Pull Request #3: Fix typo`))
+	})
+
+	It("accepts Uncategorized as the fallback category", func() {
+		gp := server.provider(clprovider.GITEA, clprovider.Options{Fallback: clprovider.TITLE, FallbackCategory: common.UncategorizedCategory.Name})
+
+		changeLog, err := gp.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changeLog.Entries(common.UncategorizedCategory.Name)).To(HaveLen(2))
+	})
+
+	It("files titles under the fallback category", func() {
+		gp := server.provider(clprovider.GITEA, clprovider.Options{Fallback: clprovider.TITLE, FallbackCategory: common.CHANGES})
+
+		changeLog, err := gp.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changeLog.Section(common.UncategorizedCategory.Name)).To(BeNil())
+		Expect(changeLog.Entries(common.CHANGES)).To(HaveLen(2))
+		Expect(changeLog.Entries(common.CHANGES)[1].Description).To(Equal("- Fix typo\n"))
+	})
+
+})
@@ -0,0 +1,98 @@
+package provider_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"changelog-pr/common"
+	clprovider "changelog-pr/provider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Output formats", func() {
+
+	var (
+		repo string
+		dir  string
+	)
+
+	BeforeEach(func() {
+		common.NewLogger("Warn", "")
+		repo = newTestRepo("",
+			testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+			testCommit{Message: "Add the widget\n\n## Changelog Inclusions\n\n### Additions\n\n- Widget\n"},
+		)
+		var err error
+		dir, err = ioutil.TempDir("", "changelog-pr-format")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(repo)
+		os.RemoveAll(dir)
+	})
+
+	It("writes the changelog as JSON, replacing the file", func() {
+		gp, err := clprovider.GetProvider(clprovider.LOCAL, "", clprovider.Options{Format: common.JSON})
+		Expect(err).NotTo(HaveOccurred())
+
+		out, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+		Expect(err).NotTo(HaveOccurred())
+		var document common.Document
+		Expect(json.Unmarshal([]byte(out), &document)).To(Succeed())
+		Expect(document.SchemaVersion).To(Equal(common.SchemaVersion))
+		Expect(document.Version).To(Equal("v0.2.0"))
+		Expect(document.Sections[0].Entries).To(HaveLen(1))
+		Expect(document.Sections[0].Entries[0].Kind).To(Equal("Commit"))
+		Expect(document.Sections[0].Entries[0].Description).To(Equal("- Widget\n"))
+
+		path := filepath.Join(dir, "changelog.json")
+		Expect(ioutil.WriteFile(path, []byte(`{"schemaVersion": 1, "version": "v0.1.0"}`), 0644)).To(Succeed())
+		_, err = gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, path)
+		Expect(err).NotTo(HaveOccurred())
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(MatchJSON(out))
+	})
+
+	It("maintains a keepachangelog.com file", func() {
+		gp, err := clprovider.GetProvider(clprovider.LOCAL, "", clprovider.Options{KeepAChangelog: &common.KeepAChangelog{
+			CompareURL: "https://git.example.com/compare/{from}...{to}",
+			Date:       time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC),
+		}})
+		Expect(err).NotTo(HaveOccurred())
+
+		path := filepath.Join(dir, "CHANGELOG.md")
+		Expect(ioutil.WriteFile(path, []byte("# Changelog\n\n## [Unreleased]\n\n## [v0.1.0] - 2021-03-01\n\n- Initial\n"), 0644)).To(Succeed())
+		for i := 0; i < 2; i++ {
+			_, err = gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, path)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(MatchRegexp(`^# Changelog\n\n## \[Unreleased\]\n\n## \[v0\.2\.0\] - 2021-03-02\n\n### Additions\n\n#### Commit #[0-9a-f]{7}\n\n- Widget\n\n## \[v0\.1\.0\] - 2021-03-01\n\n- Initial\n\n` +
+			`\[Unreleased\]: https://git\.example\.com/compare/v0\.2\.0\.\.\.HEAD\n\[v0\.2\.0\]: https://git\.example\.com/compare/v0\.1\.0\.\.\.v0\.2\.0\n$`))
+	})
+
+	It("previews the changes since the last tag", func() {
+		gp, err := clprovider.GetProvider(clprovider.LOCAL, "", clprovider.Options{})
+		Expect(err).NotTo(HaveOccurred())
+		out, err := gp.GetChangeLogFromPRMR(repo, "", common.UNRELEASED, clprovider.AuthToken{}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(HavePrefix("## Unreleased\n\n### Additions\n"))
+		Expect(out).To(ContainSubstring("- Widget\n"))
+	})
+
+	It("fails on an unknown format", func() {
+		gp, err := clprovider.GetProvider(clprovider.LOCAL, "", clprovider.Options{Format: "toml"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+		Expect(err).To(MatchError(ContainSubstring("unsupported output format")))
+	})
+
+})
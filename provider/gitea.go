@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"changelog-pr/common"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-resty/resty/v2"
+)
+
+// Gitea - Structure to hold stuff
+type Gitea struct {
+	Provider string
+	Host     string
+	Options  Options
+}
+
+type GiteaPullRequest struct {
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+	Labels  []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	MergedAt       *time.Time `json:"merged_at"`
+	MergeCommitSHA string     `json:"merge_commit_sha"`
+}
+
+// giteaRepo - The owner/repo Gitea pull requests are fetched from
+type giteaRepo struct {
+	provider *Gitea
+	auth     AuthToken
+	user     string
+	repo     string
+	client   *client
+}
+
+func parseGiteaPRNumber(msg string) (uint, error) {
+	matches := numGiteaRegex.FindAllStringSubmatch(msg, 1)
+	if len(matches) == 0 || len(matches[0]) < 2 {
+		return 0, fmt.Errorf("could not find PR number in commit message")
+	}
+	u64, err := strconv.ParseUint(matches[0][1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse PR number %q from commit message: %v", matches[0][1], err)
+	}
+	return uint(u64), nil
+}
+
+func (p *Gitea) open(remoteURL string, auth AuthToken) (requestSource, error) {
+	user, repo, err := getUserRepository(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+	common.Logger.Info(fmt.Sprintf("User/Org: %s, Repo: %s\n", user, repo))
+
+	return &giteaRepo{
+		provider: p,
+		auth:     auth,
+		user:     user,
+		repo:     repo,
+		client:   newClient(p.Options),
+	}, nil
+}
+
+// request - A REST request carrying the Gitea token, if any
+func (g *giteaRepo) request() *resty.Request {
+	req := g.client.r().SetHeader("Accept", "application/json")
+	if len(g.auth.AccessToken) > 0 {
+		req.SetHeader("Authorization", fmt.Sprintf("token %s", g.auth.AccessToken))
+	}
+	return req
+}
+
+func (g *giteaRepo) requestText() string {
+	return "Pull Request"
+}
+
+func (g *giteaRepo) match(c *object.Commit) ([]uint, error) {
+	if !strings.HasPrefix(c.Message, "Merge pull request '") {
+		return nil, nil
+	}
+	pr, err := parseGiteaPRNumber(strings.Split(c.Message, "\n")[0])
+	if err != nil {
+		return nil, err
+	}
+	return []uint{pr}, nil
+}
+
+func (g *giteaRepo) fetch(number uint) (*Request, error) {
+	// curl -sH "Authorization: token ${GITEA_TOKEN}" https://gitea.com/api/v1/repos/{owner}/{repo}/pulls/5 | jq -r '.body'
+	uri := fmt.Sprintf("%s/api/v1/repos/%s/%s/pulls/%d", hostURL(g.provider.Host), g.user, g.repo, number)
+	common.Logger.Debug(fmt.Sprintf("PR URI: %s", uri))
+	var pullRequest GiteaPullRequest
+	if err := g.client.get(g.request(), uri, cacheKey(g.provider.Provider, g.provider.Host, fmt.Sprintf("%s/%s", g.user, g.repo), number), &pullRequest); err != nil {
+		return nil, err
+	}
+
+	labels := []string{}
+	for _, l := range pullRequest.Labels {
+		labels = append(labels, l.Name)
+	}
+
+	return &Request{
+		Number:   number,
+		Title:    pullRequest.Title,
+		Body:     pullRequest.Body,
+		URL:      pullRequest.HTMLURL,
+		Labels:   labels,
+		Author:   pullRequest.User.Login,
+		MergedAt: timeOf(pullRequest.MergedAt),
+		MergeSHA: pullRequest.MergeCommitSHA,
+	}, nil
+}
+
+// GetChangelog - Collect the changelog from the PR descriptions since sinceTag
+func (p *Gitea) GetChangelog(src string, sinceTag string, release string, auth AuthToken) (*common.Changelog, error) {
+	return collect(p, p.Options, src, sinceTag, release, auth)
+}
+
+// GetChangeLogFromPRMR - Get the changelog details from the PR/MR description
+func (p *Gitea) GetChangeLogFromPRMR(src string, sinceTag string, release string, auth AuthToken, fileName string) (string, error) {
+	return render(p, p.Options, src, sinceTag, release, auth, fileName)
+}
@@ -0,0 +1,69 @@
+package provider_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	clprovider "changelog-pr/provider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Gitea", func() {
+
+	var (
+		server *testServer
+		repo   string
+	)
+
+	BeforeEach(func() {
+		server = newTestServer(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v1/repos/owner/repo/pulls/12":
+				fmt.Fprint(w, `{"body": "## Changelog Inclusions\r\n\r\n### Additions\r\n\r\n- Gitea addition\r\n", "html_url": "https://gitea.example.com/owner/repo/pulls/12"}`)
+			case "/api/v1/repos/owner/repo/pulls/13":
+				fmt.Fprint(w, `{"body": "## Changelog Inclusions\n\n### Fixes\n\n- Gitea fix\n", "html_url": "https://gitea.example.com/owner/repo/pulls/13"}`)
+			default:
+				http.NotFound(w, r)
+			}
+		})
+		repo = newTestRepo("https://gitea.example.com/owner/repo.git",
+			testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+			testCommit{Message: "Merge pull request 'Add a thing' (#12) from feature/thing into main\n"},
+			testCommit{Message: "Direct commit without a pull request\n"},
+			testCommit{Message: "Merge pull request 'Fix a thing' (#13) from bugfix/thing into main\n"},
+		)
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(repo)
+	})
+
+	It("collects the changelog from merged pull requests", func() {
+		gp := server.provider(clprovider.GITEA, clprovider.Options{})
+
+		out, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{AccessToken: "secret"}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal(`## v0.2.0
+
+### Additions
+
+#### [Pull Request #12](https://gitea.example.com/owner/repo/pulls/12)
+
+- Gitea addition
+
+
+### Bug Fixes
+
+#### [Pull Request #13](https://gitea.example.com/owner/repo/pulls/13)
+
+- Gitea fix
+
+`))
+		Expect(server.Auth()).To(ConsistOf("token secret", "token secret"))
+	})
+
+})
@@ -1,20 +1,15 @@
 package provider
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/blang/semver/v4"
-	"github.com/go-git/go-git/v5"
-
-	// . "github.com/go-git/go-git/v5/_examples"
 	"changelog-pr/common"
 
-	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-resty/resty/v2"
 )
@@ -23,17 +18,57 @@ import (
 type Github struct {
 	Provider string
 	Host     string
+	Options  Options
 }
 
 type PRBody struct {
-	Body  string `json:"body"`
-	Links struct {
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	MergedAt       *time.Time `json:"merged_at"`
+	MergeCommitSHA string     `json:"merge_commit_sha"`
+	Links          struct {
 		HTML struct {
 			HREF string `json:"href"`
 		} `json:"html"`
 	} `json:"_links"`
 }
 
+type PRCommitPull struct {
+	Number   uint    `json:"number"`
+	MergedAt *string `json:"merged_at"`
+}
+
+// githubRepo - The owner/repo pull requests are fetched from, and how they are detected
+type githubRepo struct {
+	provider   *Github
+	auth       AuthToken
+	user       string
+	repo       string
+	strategies []string
+	client     *client
+	// squashed holds the numbers only found in "(#123)" subjects, which may be
+	// issues rather than pull requests
+	squashed sync.Map
+}
+
+func parseSquashPRNumber(msg string) (uint, error) {
+	matches := numSquashRegex.FindAllStringSubmatch(strings.TrimSpace(msg), 1)
+	if len(matches) == 0 || len(matches[0]) < 2 {
+		return 0, fmt.Errorf("could not find PR number in commit message")
+	}
+	u64, err := strconv.ParseUint(matches[0][1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse PR number %q from commit message: %v", matches[0][1], err)
+	}
+	return uint(u64), nil
+}
+
 func parsePRNumber(msg string) (uint, error) {
 	matches := numRegex.FindAllStringSubmatch(msg, 1)
 	if len(matches) == 0 || len(matches[0]) < 2 {
@@ -49,220 +84,139 @@ func parsePRNumber(msg string) (uint, error) {
 func getUserRepository(url string) (string, string, error) {
 	// git@github.com:Maahsome/changelog-pr.git
 	// https://github.com/Maahsome/changelog-pr.git
+	// https://user@bitbucket.org/Maahsome/changelog-pr.git
+	// ssh://git@github.example.com:2222/Maahsome/changelog-pr.git
+	// https://gitlab.example.com/Maahsome/tools/changelog-pr
 
-	var matches [][]string
-
-	var gitRegexPattern string
-	var httpRegexPattern string
-
-	if strings.HasSuffix(url, ".git") {
-		gitRegexPattern = `:(.+)\/(.+).git`
-		httpRegexPattern = `\.com\/(.+)\/(.+).git`
-	} else {
-		gitRegexPattern = `:(.+)\/(.+)$`
-		httpRegexPattern = `\.com\/(.+)\/(.+)$`
-	}
-
-	if strings.Contains(url, "git@") {
-		var gitRegex = regexp.MustCompile(gitRegexPattern)
-		matches = gitRegex.FindAllStringSubmatch(url, -1)
-		if len(matches) == 0 || len(matches[0]) == 0 || len(matches[0][1]) == 0 || len(matches[0][2]) == 0 {
-			return "", "", errors.New("failed to extract git user/repository")
-		}
-	}
-	if strings.Contains(url, "https://") {
-		var httpsRegex = regexp.MustCompile(httpRegexPattern)
-		matches = httpsRegex.FindAllStringSubmatch(url, -1)
-		if len(matches) == 0 || len(matches[0]) == 0 || len(matches[0][1]) == 0 || len(matches[0][2]) == 0 {
-			return "", "", errors.New("failed to extract git user/repository")
-		}
+	matches := remoteRegex.FindStringSubmatch(url)
+	if len(matches) < 3 || len(matches[1]) == 0 || len(matches[2]) == 0 {
+		return "", "", errors.New("failed to extract git user/repository")
 	}
 
-	return matches[0][1], matches[0][2], nil
+	return matches[1], matches[2], nil
 }
 
-// GetChangeLogSincePR - Get the changelog details from the PR/MR description
-func (p *Github) GetChangeLogFromPRMR(src string, sincePR string, release string, auth AuthToken, fileName string) (string, error) {
-
-	var (
-		resp    *resty.Response
-		resperr error
-	)
-
-	r, err := git.PlainOpen(src)
-	if err != nil {
-		return "", errors.New("failed generation of changelog")
-	}
-
-	head, rerr := r.Head()
-	if rerr != nil {
-		return "", errors.New("failed generation of changelog")
-	}
-	common.Logger.Info(fmt.Sprintf("HEAD: %s", head.Name().Short()))
-
-	c, cerr := r.Config()
-	if cerr != nil {
-		return "", errors.New("failed generation of changelog")
-	}
-	common.Logger.Debug(fmt.Sprintf("Remote URL: %s", c.Remotes["origin"].URLs[0]))
-	user, repo, rerr := getUserRepository(c.Remotes["origin"].URLs[0])
-	if rerr != nil {
-		return "", errors.New("failed generation of changelog")
+// apiURL - The REST API root for the configured host, GitHub Enterprise Server
+// serves the API below /api/v3 on the instance host
+func (p *Github) apiURL() string {
+	if len(p.Host) == 0 || p.Host == "github.com" {
+		return "https://api.github.com"
 	}
-	common.Logger.Info(fmt.Sprintf("User/Org: %s, Repo: %s\n", user, repo))
+	return fmt.Sprintf("%s/api/v3", hostURL(p.Host))
+}
 
-	tagrefs, err := r.Tags()
+func (p *Github) open(remoteURL string, auth AuthToken) (requestSource, error) {
+	user, repo, err := getUserRepository(remoteURL)
 	if err != nil {
-		return "", errors.New("failed generation of changelog")
+		return nil, err
 	}
+	common.Logger.Info(fmt.Sprintf("User/Org: %s, Repo: %s\n", user, repo))
 
-	err = tagrefs.ForEach(func(t *plumbing.Reference) error {
-		common.Logger.Debug(fmt.Sprintf("Tag Name: %s", t.Name().String()))
-		var (
-			nv semver.Version
-			lv semver.Version
-		)
-
-		if len(sincePR) > 0 {
-			if strings.HasSuffix(t.Name().String(), sincePR) {
-				common.Logger.Debug(t.Hash())
-				lastTag = t
-			}
-		} else {
-			//refs/tags/v0.1.0
-			var refRegex = regexp.MustCompile(`\/tags\/v(.+)`)
-			newMatches := refRegex.FindAllStringSubmatch(t.Name().String(), -1)
-			if len(newMatches) > 0 && len(newMatches[0]) > 0 && len(newMatches[0][1]) > 0 {
-				nv, err = semver.Parse(newMatches[0][1])
-				if err != nil {
-					common.Logger.Error(fmt.Sprintf("Error parsing SemVer for %s", newMatches[0][1]))
-					return nil
-				}
-			}
-			if lastTag != nil {
-				lastMatches := refRegex.FindAllStringSubmatch(lastTag.Name().String(), -1)
-				if len(lastMatches) > 0 && len(lastMatches[0]) > 0 && len(lastMatches[0][1]) > 0 {
-					lv, err = semver.Parse(lastMatches[0][1])
-					if err != nil {
-						common.Logger.Error(fmt.Sprintf("Error parsing SemVer for %s", lastMatches[0][1]))
-						return nil
-					}
-					if nv.GTE(lv) {
-						lastTag = t
-					}
-				}
-			} else {
-				lastTag = t
-			}
-		}
-		return nil
-	})
-	if err != nil {
-		return "", errors.New("failed generation of changelog")
-	}
-	common.Logger.Info(fmt.Sprintf("Last Tag/Hash: %s (%s)", lastTag.Name().String(), lastTag.Hash()))
+	// squash detection is opt-in, as commits citing an issue "(#45)" look the same
+	strategies := p.Options.strategiesFor(user, repo, []string{MERGE})
+	common.Logger.Info(fmt.Sprintf("Merge detection strategies: %s", strings.Join(strategies, ", ")))
+
+	return &githubRepo{
+		provider:   p,
+		auth:       auth,
+		user:       user,
+		repo:       repo,
+		strategies: strategies,
+		client:     newClient(p.Options),
+	}, nil
+}
 
-	// Gets the HEAD history from HEAD, just like this command:
-	// ... retrieves the branch pointed by HEAD
-	ref, err := r.Head()
-	if err != nil {
-		return "", errors.New("failed generation of changelog")
+// request - A REST request carrying the GitHub headers and the token, if any
+func (g *githubRepo) request() *resty.Request {
+	req := g.client.r().SetHeader("Accept", "application/vnd.github.v3+json")
+	if len(g.auth.AccessToken) > 0 {
+		req.SetHeader("Authorization", fmt.Sprintf("token %s", g.auth.AccessToken))
 	}
+	return req
+}
 
-	cIter, err := r.Log(&git.LogOptions{From: ref.Hash(), Order: git.LogOrderCommitterTime})
-	if err != nil {
-		return "", errors.New("failed generation of changelog")
-	}
+func (g *githubRepo) requestText() string {
+	return "Pull Request"
+}
 
-	hasHash := false
-	err = cIter.ForEach(func(c *object.Commit) error {
-		if c.Hash == lastTag.Hash() {
-			hasHash = true
-		}
-		return nil
-	})
-	if err != nil {
-		return "", errors.New("failed generation of changelog")
-	}
-	PRs := []string{}
-	if hasHash {
-		findingHash := true
-		cIter, err := r.Log(&git.LogOptions{From: ref.Hash(), Order: git.LogOrderCommitterTime})
+func (g *githubRepo) match(c *object.Commit) ([]uint, error) {
+	subject := strings.Split(c.Message, "\n")[0]
+	switch {
+	case hasStrategy(g.strategies, MERGE) && strings.HasPrefix(c.Message, "Merge pull request #"):
+		pr, err := parsePRNumber(subject)
 		if err != nil {
-			return "", errors.New("failed generation of changelog")
+			return nil, err
 		}
-		err = cIter.ForEach(func(c *object.Commit) error {
-			if c.Hash == lastTag.Hash() {
-				findingHash = false
-				return nil
-			}
-			if findingHash {
-				if strings.HasPrefix(c.Message, "Merge pull request #") {
-					pr, err := parsePRNumber(strings.Split(c.Message, "\n")[0])
-					if err != nil {
-						common.Logger.WithError(err).Error("Bad PR Parse")
-					}
-					PRs = append(PRs, fmt.Sprintf("%d", pr))
-					common.Logger.Info(fmt.Sprintf("%s %s\n", c.ID(), strings.Split(c.Message, "\n")[0]))
-				}
-				return nil
-			}
-			return nil
-		})
+		return []uint{pr}, nil
+	case hasStrategy(g.strategies, SQUASH) && numSquashRegex.MatchString(strings.TrimSpace(subject)):
+		pr, err := parseSquashPRNumber(subject)
 		if err != nil {
-			return "", errors.New("failed generation of changelog")
+			return nil, err
 		}
-	} else {
-		common.Logger.Fatal(fmt.Sprintf("The TAG you specified was NOT in the currently selected BRANCH: %s", head.Name().Short()))
+		g.squashed.Store(pr, true)
+		return []uint{pr}, nil
+	case hasStrategy(g.strategies, LOOKUP):
+		return g.lookupPRs(c.Hash.String())
 	}
-	// curl -sH "Accept: application/vnd.github.v3+json" https://api.github.com/repos/splicemachine/splicectl/pulls/5 | jq -r '.body'
-	restClient := resty.New()
-
-	changeLog := common.Changelog{}
-	changeLog.Version = release
-
-	for _, p := range PRs {
-		uri := fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls/%s", user, repo, p)
-		common.Logger.Debug(fmt.Sprintf("PR URI: %s", uri))
-		if len(auth.AccessToken) > 0 {
-			resp, resperr = restClient.R().
-				SetHeader("Accept", "application/vnd.github.v3+json").
-				SetHeader("Authorization", fmt.Sprintf("token %s", auth.AccessToken)).
-				Get(uri)
-		} else {
-			resp, resperr = restClient.R().
-				SetHeader("Accept", "application/vnd.github.v3+json").
-				Get(uri)
-		}
-		if resperr != nil {
-			common.Logger.WithError(resperr).Error("Error getting PR")
-		}
-
-		var body PRBody
+	return nil, nil
+}
 
-		marshErr := json.Unmarshal(resp.Body(), &body)
-		if marshErr != nil {
-			common.Logger.Error("Could not unmarshall data", marshErr)
+// lookupPRs - Ask GitHub which merged pull requests contain a commit, used for
+// rebase merges where the commit message carries no PR reference
+func (g *githubRepo) lookupPRs(sha string) ([]uint, error) {
+	// curl -sH "Accept: application/vnd.github.v3+json" https://api.github.com/repos/splicemachine/splicectl/commits/{sha}/pulls | jq -r '.[].number'
+	uri := fmt.Sprintf("%s/repos/%s/%s/commits/%s/pulls", g.provider.apiURL(), g.user, g.repo, sha)
+	common.Logger.Debug(fmt.Sprintf("Commit PRs URI: %s", uri))
+	var pulls []PRCommitPull
+	if err := g.client.get(g.request(), uri, "", &pulls); err != nil {
+		return nil, err
+	}
+
+	numbers := []uint{}
+	for _, pull := range pulls {
+		if pull.MergedAt != nil {
+			numbers = append(numbers, pull.Number)
 		}
+	}
+	return numbers, nil
+}
 
-		err := common.ParseMarkdown(body.Body, p, &changeLog, "Pull Request", body.Links.HTML.HREF)
-		if err != nil {
-			common.Logger.Error("Could not parse the markdown")
+func (g *githubRepo) fetch(number uint) (*Request, error) {
+	// curl -sH "Accept: application/vnd.github.v3+json" https://api.github.com/repos/splicemachine/splicectl/pulls/5 | jq -r '.body'
+	uri := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", g.provider.apiURL(), g.user, g.repo, number)
+	common.Logger.Debug(fmt.Sprintf("PR URI: %s", uri))
+	var body PRBody
+	if err := g.client.get(g.request(), uri, cacheKey(g.provider.Provider, g.provider.Host, fmt.Sprintf("%s/%s", g.user, g.repo), number), &body); err != nil {
+		if _, squashed := g.squashed.Load(number); squashed && isNotFound(err) {
+			common.Logger.Warn(fmt.Sprintf("#%d is not a pull request, e.g. a commit citing an issue, it was left out", number))
+			return nil, nil
 		}
+		return nil, err
 	}
 
-	markdown, err := changeLog.Template()
-	if err != nil {
-		return "", errors.New("failed generation of changelog")
+	labels := []string{}
+	for _, l := range body.Labels {
+		labels = append(labels, l.Name)
 	}
 
-	if len(fileName) > 0 {
-		err := changeLog.WriteFile(fileName)
-		if err != nil {
-			return "", errors.New("failed to write to the output file")
-		}
-		return "Changelog data has been saved.", nil
-	}
+	return &Request{
+		Number:   number,
+		Title:    body.Title,
+		Body:     body.Body,
+		URL:      body.Links.HTML.HREF,
+		Labels:   labels,
+		Author:   body.User.Login,
+		MergedAt: timeOf(body.MergedAt),
+		MergeSHA: body.MergeCommitSHA,
+	}, nil
+}
+
+// GetChangelog - Collect the changelog from the PR descriptions since sinceTag
+func (p *Github) GetChangelog(src string, sinceTag string, release string, auth AuthToken) (*common.Changelog, error) {
+	return collect(p, p.Options, src, sinceTag, release, auth)
+}
 
-	return string(markdown[:]), nil
+// GetChangeLogFromPRMR - Get the changelog details from the PR/MR description
+func (p *Github) GetChangeLogFromPRMR(src string, sinceTag string, release string, auth AuthToken, fileName string) (string, error) {
+	return render(p, p.Options, src, sinceTag, release, auth, fileName)
 }
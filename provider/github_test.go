@@ -0,0 +1,131 @@
+package provider_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	clprovider "changelog-pr/provider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Github", func() {
+
+	var (
+		server *testServer
+		repo   string
+	)
+
+	BeforeEach(func() {
+		server = newTestServer(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, "/api/v3/repos/owner/repo/commits/") {
+				fmt.Fprint(w, `[{"number": 9, "merged_at": "2021-03-01T10:00:00Z"}, {"number": 10, "merged_at": null}]`)
+				return
+			}
+			switch r.URL.Path {
+			case "/api/v3/repos/owner/repo/pulls/8", "/api/v3/repos/owner/repo/pulls/9":
+				pr := strings.TrimPrefix(r.URL.Path, "/api/v3/repos/owner/repo/pulls/")
+				fmt.Fprintf(w, `{"body": "## Changelog Inclusions\n\n### Changes\n\n- Change from %s\n", "_links": {"html": {"href": "https://github.example.internal/owner/repo/pull/%s"}}}`, pr, pr)
+			case "/api/v3/repos/owner/repo/pulls/7":
+				fmt.Fprint(w, `{"body": "## Changelog Inclusions\r\n\r\n### Additions\r\n\r\n- Enterprise addition\r\n", "_links": {"html": {"href": "https://github.example.internal/owner/repo/pull/7"}}}`)
+			default:
+				http.NotFound(w, r)
+			}
+		})
+		repo = newTestRepo("git@github.example.internal:owner/repo.git",
+			testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+			testCommit{Message: "Merge pull request #7 from owner/feature\n\nAdd a thing"},
+			testCommit{Message: "Change a squashed thing (#8)\n\n* first\n* second"},
+			testCommit{Message: "Rebased change, part one"},
+			testCommit{Message: "Rebased change, part two"},
+			testCommit{Message: "Fix crash (#45)"},
+		)
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(repo)
+	})
+
+	It("routes API calls through the enterprise host", func() {
+		gp := server.provider(clprovider.GITHUB, clprovider.Options{})
+
+		out, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(server.Paths()).To(ContainElement("/api/v3/repos/owner/repo/pulls/7"))
+		Expect(out).To(ContainSubstring("#### [Pull Request #7](https://github.example.internal/owner/repo/pull/7)\n\n- Enterprise addition\n"))
+	})
+
+	It("detects merge commits only by default", func() {
+		gp := server.provider(clprovider.GITHUB, clprovider.Options{})
+
+		_, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(server.Paths()).To(Equal([]string{
+			"/api/v3/repos/owner/repo/pulls/7",
+		}))
+	})
+
+	It("detects squash merged pull requests when asked to, leaving out cited issues", func() {
+		gp := server.provider(clprovider.GITHUB, clprovider.Options{Strategies: map[string][]string{"default": {"merge", "squash"}}})
+
+		out, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(server.Paths()).To(ConsistOf(
+			"/api/v3/repos/owner/repo/pulls/45",
+			"/api/v3/repos/owner/repo/pulls/8",
+			"/api/v3/repos/owner/repo/pulls/7",
+		))
+		Expect(out).To(ContainSubstring("- Change from 8\n"))
+		Expect(out).NotTo(ContainSubstring("#45"))
+	})
+
+	It("fails on a missing pull request of a merge commit", func() {
+		os.RemoveAll(repo)
+		repo = newTestRepo("git@github.example.internal:owner/repo.git",
+			testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+			testCommit{Message: "Merge pull request #46 from owner/gone"},
+		)
+		gp := server.provider(clprovider.GITHUB, clprovider.Options{})
+
+		_, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+		Expect(err).To(MatchError(ContainSubstring("/api/v3/repos/owner/repo/pulls/46 failed after 1 attempt(s): 404 Not Found")))
+	})
+
+	It("looks up rebase merged pull requests when the repository enables it", func() {
+		opts := clprovider.Options{
+			Strategies: map[string][]string{
+				"default":    {"merge"},
+				"owner/repo": {"merge", "squash", "lookup"},
+			},
+		}
+		gp := server.provider(clprovider.GITHUB, opts)
+
+		out, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal(`## v0.2.0
+
+### Additions
+
+#### [Pull Request #7](https://github.example.internal/owner/repo/pull/7)
+
+- Enterprise addition
+
+
+### Changes
+
+#### [Pull Request #9](https://github.example.internal/owner/repo/pull/9)
+
+- Change from 9
+
+#### [Pull Request #8](https://github.example.internal/owner/repo/pull/8)
+
+- Change from 8
+
+`))
+	})
+
+})
@@ -1,38 +1,55 @@
 package provider
 
 import (
-	"encoding/json"
-	"errors"
 	"fmt"
 	"net/url"
-	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/blang/semver/v4"
-	"github.com/go-git/go-git/v5"
-	"github.com/go-resty/resty/v2"
-
-	// . "github.com/go-git/go-git/v5/_examples"
 	"changelog-pr/common"
 
-	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-resty/resty/v2"
 )
 
-// Github - Structure to hold stuff
+// Gitlab - Structure to hold stuff
 type Gitlab struct {
 	Provider string
 	Host     string
+	Options  Options
 }
 
 type MRDescription struct {
-	Description string `json:"description"`
-	WebURL      string `json:"web_url"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	WebURL      string   `json:"web_url"`
+	Labels      []string `json:"labels"`
+	Author      struct {
+		Username string `json:"username"`
+	} `json:"author"`
+	MergedAt        *time.Time `json:"merged_at"`
+	MergeCommitSHA  string     `json:"merge_commit_sha"`
+	SquashCommitSHA string     `json:"squash_commit_sha"`
+}
+
+type MRCommitMergeRequest struct {
+	IID   uint   `json:"iid"`
+	State string `json:"state"`
+}
+
+// gitlabRepo - A GitLab project, addressed by its URL encoded path in API calls
+type gitlabRepo struct {
+	provider   *Gitlab
+	auth       AuthToken
+	project    string
+	glSlug     string
+	strategies []string
+	client     *client
 }
 
 func parseMRNumber(msg string) (uint, error) {
-	matches := numBangRegex.FindAllStringSubmatch(msg, 1)
+	matches := numMergeRequestRegex.FindAllStringSubmatch(msg, 1)
 	if len(matches) == 0 || len(matches[0]) < 2 {
 		return 0, fmt.Errorf("could not find MR number in commit message")
 	}
@@ -43,217 +60,104 @@ func parseMRNumber(msg string) (uint, error) {
 	return uint(u64), nil
 }
 
-// func getUserRepository(url string) (string, string, error) {
-// 	// git@github.com:Maahsome/changelog-pr.git
-// 	// https://github.com/Maahsome/changelog-pr.git
-
-// 	var matches [][]string
-
-// 	if strings.Contains(url, "git@") {
-// 		var gitRegex = regexp.MustCompile(`:(.+)\/(.+).git`)
-// 		matches = gitRegex.FindAllStringSubmatch(url, -1)
-// 		if len(matches) == 0 || len(matches[0]) == 0 || len(matches[0][1]) == 0 || len(matches[0][2]) == 0 {
-// 			return "", "", errors.New("failed to extract git user/repository")
-// 		}
-// 	}
-// 	if strings.Contains(url, "https://") {
-// 		var httpsRegex = regexp.MustCompile(`\.com\/(.+)\/(.+).git`)
-// 		matches = httpsRegex.FindAllStringSubmatch(url, -1)
-// 		if len(matches) == 0 || len(matches[0]) == 0 || len(matches[0][1]) == 0 || len(matches[0][2]) == 0 {
-// 			return "", "", errors.New("failed to extract git user/repository")
-// 		}
-// 	}
-
-// 	return matches[0][1], matches[0][2], nil
-// }
-
-// GetChangeLogSincePR - Get the changelog details from the PR/MR description
-func (p *Gitlab) GetChangeLogFromPRMR(src string, sincePR string, release string, auth AuthToken, fileName string) (string, error) {
-
-	var (
-		resp    *resty.Response
-		resperr error
-	)
-
-	r, err := git.PlainOpen(src)
+func (p *Gitlab) open(remoteURL string, auth AuthToken) (requestSource, error) {
+	user, repo, err := getUserRepository(remoteURL)
 	if err != nil {
-		return "", errors.New("failed generation of changelog")
-	}
-
-	head, rerr := r.Head()
-	if rerr != nil {
-		return "", errors.New("failed generation of changelog")
-	}
-	common.Logger.Info(fmt.Sprintf("HEAD: %s", head.Name().Short()))
-
-	c, cerr := r.Config()
-	if cerr != nil {
-		return "", errors.New("failed generation of changelog")
-	}
-	common.Logger.Debug(fmt.Sprintf("Remote URL: %s", c.Remotes["origin"].URLs[0]))
-	user, repo, rerr := getUserRepository(c.Remotes["origin"].URLs[0])
-	if rerr != nil {
-		return "", errors.New("failed generation of changelog")
+		return nil, err
 	}
 	common.Logger.Info(fmt.Sprintf("User/Org: %s, Repo: %s\n", user, repo))
 
-	tagrefs, err := r.Tags()
-	if err != nil {
-		return "", errors.New("failed generation of changelog")
-	}
-
-	err = tagrefs.ForEach(func(t *plumbing.Reference) error {
-		common.Logger.Debug(fmt.Sprintf("Tag Name: %s", t.Name().String()))
-		var (
-			nv semver.Version
-			lv semver.Version
-		)
-
-		if len(sincePR) > 0 {
-			if strings.HasSuffix(t.Name().String(), sincePR) {
-				common.Logger.Debug(t.Hash())
-				lastTag = t
-			}
-		} else {
-			//refs/tags/v0.1.0
-			var refRegex = regexp.MustCompile(`\/tags\/v(.+)`)
-			newMatches := refRegex.FindAllStringSubmatch(t.Name().String(), -1)
-			if len(newMatches) > 0 && len(newMatches[0]) > 0 && len(newMatches[0][1]) > 0 {
-				nv, err = semver.Parse(newMatches[0][1])
-				if err != nil {
-					common.Logger.Error(fmt.Sprintf("Error parsing SemVer for %s", newMatches[0][1]))
-					return nil
-				}
-			}
-			if lastTag != nil {
-				lastMatches := refRegex.FindAllStringSubmatch(lastTag.Name().String(), -1)
-				if len(lastMatches) > 0 && len(lastMatches[0]) > 0 && len(lastMatches[0][1]) > 0 {
-					lv, err = semver.Parse(lastMatches[0][1])
-					if err != nil {
-						common.Logger.Error(fmt.Sprintf("Error parsing SemVer for %s", lastMatches[0][1]))
-						return nil
-					}
-					if nv.GTE(lv) {
-						lastTag = t
-					}
-				}
-			} else {
-				lastTag = t
-			}
-		}
-		return nil
-	})
-	if err != nil {
-		return "", errors.New("failed generation of changelog")
-	}
-	common.Logger.Info(fmt.Sprintf("Last Tag/Hash: %s (%s)", lastTag.Name().String(), lastTag.Hash()))
+	// fast-forward and squash merges leave no "See merge request" commit behind
+	strategies := p.Options.strategiesFor(user, repo, []string{MERGE, LOOKUP})
+	common.Logger.Info(fmt.Sprintf("Merge detection strategies: %s", strings.Join(strategies, ", ")))
+
+	return &gitlabRepo{
+		provider:   p,
+		auth:       auth,
+		project:    fmt.Sprintf("%s/%s", user, repo),
+		glSlug:     url.PathEscape(fmt.Sprintf("%s/%s", user, repo)),
+		strategies: strategies,
+		client:     newClient(p.Options),
+	}, nil
+}
 
-	// Gets the HEAD history from HEAD, just like this command:
-	// ... retrieves the branch pointed by HEAD
-	ref, err := r.Head()
-	if err != nil {
-		return "", errors.New("failed generation of changelog")
+// request - A REST request carrying the GitLab headers and the token, if any
+func (g *gitlabRepo) request() *resty.Request {
+	req := g.client.r().SetHeader("Accept", "application/json")
+	if len(g.auth.AccessToken) > 0 {
+		req.SetHeader("PRIVATE-TOKEN", g.auth.AccessToken)
 	}
+	return req
+}
 
-	cIter, err := r.Log(&git.LogOptions{From: ref.Hash(), Order: git.LogOrderCommitterTime})
-	if err != nil {
-		return "", errors.New("failed generation of changelog")
-	}
+func (g *gitlabRepo) requestText() string {
+	return "Merge Request"
+}
 
-	hasHash := false
-	err = cIter.ForEach(func(c *object.Commit) error {
-		if c.Hash == lastTag.Hash() {
-			hasHash = true
-		}
-		return nil
-	})
-	if err != nil {
-		return "", errors.New("failed generation of changelog")
-	}
-	MRs := []string{}
-	if hasHash {
-		findingHash := true
-		cIter, err := r.Log(&git.LogOptions{From: ref.Hash(), Order: git.LogOrderCommitterTime})
+func (g *gitlabRepo) match(c *object.Commit) ([]uint, error) {
+	switch {
+	case hasStrategy(g.strategies, MERGE) && strings.Contains(c.Message, "See merge request"):
+		mr, err := parseMRNumber(c.Message)
 		if err != nil {
-			return "", errors.New("failed generation of changelog")
+			return nil, err
 		}
-		err = cIter.ForEach(func(c *object.Commit) error {
-			if c.Hash == lastTag.Hash() {
-				findingHash = false
-				return nil
-			}
-			if findingHash {
-				common.Logger.Trace(c.Message)
-				if strings.Contains(c.Message, "See merge request") {
-					messageLines := strings.Split(c.Message, "\n")
-					lastLine := messageLines[len(messageLines)-1]
-					common.Logger.Info("lastLine", lastLine)
-					mr, err := parseMRNumber(lastLine)
-					if err != nil {
-						common.Logger.WithError(err).Error("Bad MR Parse")
-					}
-					MRs = append(MRs, fmt.Sprintf("%d", mr))
-					common.Logger.Info(fmt.Sprintf("%s %s\n", c.ID(), lastLine))
-				}
-				return nil
-			}
-			return nil
-		})
-		if err != nil {
-			return "", errors.New("failed generation of changelog")
-		}
-	} else {
-		common.Logger.Fatal(fmt.Sprintf("The TAG you specified was NOT in the currently selected BRANCH: %s", head.Name().Short()))
+		return []uint{mr}, nil
+	case hasStrategy(g.strategies, LOOKUP):
+		return g.lookupMRs(c.Hash.String())
 	}
-	restClient := resty.New()
-
-	changeLog := common.Changelog{}
-	changeLog.Version = release
-	glSlug := url.PathEscape(fmt.Sprintf("%s/%s", user, repo))
-	for _, m := range MRs {
-		uri := fmt.Sprintf("https://%s/api/v4/projects/%s/merge_requests/%s", p.Host, glSlug, m)
-		common.Logger.Debug(fmt.Sprintf("PR URI: %s", uri))
-		if len(auth.AccessToken) > 0 {
-			resp, resperr = restClient.R().
-				SetHeader("Accept", "application/json").
-				SetHeader("PRIVATE-TOKEN", auth.AccessToken).
-				Get(uri)
-		} else {
-			resp, resperr = restClient.R().
-				SetHeader("Accept", "application/json").
-				Get(uri)
-		}
-		if resperr != nil {
-			common.Logger.WithError(resperr).Error("Error getting MR")
-		}
-
-		common.Logger.Trace("MR Response", string(resp.Body()[:]))
+	return nil, nil
+}
 
-		var description MRDescription
+// lookupMRs - Ask GitLab which merged merge requests contain a commit, used for
+// fast-forward and squash merges that leave no merge commit behind
+func (g *gitlabRepo) lookupMRs(sha string) ([]uint, error) {
+	uri := fmt.Sprintf("%s/api/v4/projects/%s/repository/commits/%s/merge_requests", hostURL(g.provider.Host), g.glSlug, sha)
+	common.Logger.Debug(fmt.Sprintf("Commit MRs URI: %s", uri))
+	var mergeRequests []MRCommitMergeRequest
+	if err := g.client.get(g.request(), uri, "", &mergeRequests); err != nil {
+		return nil, err
+	}
 
-		marshErr := json.Unmarshal(resp.Body(), &description)
-		if marshErr != nil {
-			common.Logger.Error("Could not unmarshall data", marshErr)
-		}
-
-		err := common.ParseMarkdown(description.Description, m, &changeLog, "Merge Request", description.WebURL)
-		if err != nil {
-			common.Logger.Error("Could not parse the markdown")
+	iids := []uint{}
+	for _, mr := range mergeRequests {
+		if mr.State == "merged" {
+			iids = append(iids, mr.IID)
 		}
 	}
+	return iids, nil
+}
 
-	markdown, err := changeLog.Template()
-	if err != nil {
-		return "", errors.New("failed generation of changelog")
+func (g *gitlabRepo) fetch(number uint) (*Request, error) {
+	uri := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests/%d", hostURL(g.provider.Host), g.glSlug, number)
+	common.Logger.Debug(fmt.Sprintf("PR URI: %s", uri))
+	var description MRDescription
+	if err := g.client.get(g.request(), uri, cacheKey(g.provider.Provider, g.provider.Host, g.project, number), &description); err != nil {
+		return nil, err
 	}
 
-	if len(fileName) > 0 {
-		err := changeLog.WriteFile(fileName)
-		if err != nil {
-			return "", errors.New("failed to write to the output file")
-		}
-		return "Changelog data has been saved.", nil
+	if len(description.MergeCommitSHA) == 0 {
+		// fast-forward merges of squashed MRs only record the squash commit
+		description.MergeCommitSHA = description.SquashCommitSHA
 	}
 
-	return string(markdown[:]), nil
+	return &Request{
+		Number:   number,
+		Title:    description.Title,
+		Body:     description.Description,
+		URL:      description.WebURL,
+		Labels:   description.Labels,
+		Author:   description.Author.Username,
+		MergedAt: timeOf(description.MergedAt),
+		MergeSHA: description.MergeCommitSHA,
+	}, nil
+}
+
+// GetChangelog - Collect the changelog from the MR descriptions since sinceTag
+func (p *Gitlab) GetChangelog(src string, sinceTag string, release string, auth AuthToken) (*common.Changelog, error) {
+	return collect(p, p.Options, src, sinceTag, release, auth)
+}
+
+// GetChangeLogFromPRMR - Get the changelog details from the PR/MR description
+func (p *Gitlab) GetChangeLogFromPRMR(src string, sinceTag string, release string, auth AuthToken, fileName string) (string, error) {
+	return render(p, p.Options, src, sinceTag, release, auth, fileName)
 }
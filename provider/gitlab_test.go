@@ -0,0 +1,77 @@
+package provider_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	clprovider "changelog-pr/provider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Gitlab", func() {
+
+	var (
+		server *testServer
+		repo   string
+	)
+
+	BeforeEach(func() {
+		server = newTestServer(func(w http.ResponseWriter, r *http.Request) {
+			path := r.URL.EscapedPath()
+			project := "/api/v4/projects/group%2Fsub%2Fproject"
+			switch {
+			case strings.HasPrefix(path, project+"/repository/commits/"):
+				fmt.Fprint(w, `[{"iid": 4, "state": "merged"}, {"iid": 5, "state": "opened"}]`)
+			case strings.HasPrefix(path, project+"/merge_requests/"):
+				mr := strings.TrimPrefix(path, project+"/merge_requests/")
+				fmt.Fprintf(w, `{"description": "## Changelog Inclusions\n\n### Changes\n\n- Change from %s\n", "web_url": "https://gitlab.example.com/group/sub/project/-/merge_requests/%s"}`, mr, mr)
+			default:
+				http.NotFound(w, r)
+			}
+		})
+		repo = newTestRepo("git@gitlab.example.com:group/sub/project.git",
+			testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+			testCommit{Message: "Merge branch 'feature' into 'main'\n\nChange a thing\n\nSee merge request group/sub/project!3\n\nChangelog: changed"},
+			testCommit{Message: "Fast-forwarded change, part one"},
+			testCommit{Message: "Fast-forwarded change, part two"},
+		)
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(repo)
+	})
+
+	It("finds the merge request reference on any line of a merge commit", func() {
+		gp := server.provider(clprovider.GITLAB, clprovider.Options{Strategies: map[string][]string{"default": {"merge"}}})
+
+		_, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(server.Paths()).To(Equal([]string{"/api/v4/projects/group%2Fsub%2Fproject/merge_requests/3"}))
+	})
+
+	It("asks the API for the merge requests of fast-forwarded commits by default", func() {
+		gp := server.provider(clprovider.GITLAB, clprovider.Options{})
+
+		out, err := gp.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal(`## v0.2.0
+
+### Changes
+
+#### [Merge Request #4](https://gitlab.example.com/group/sub/project/-/merge_requests/4)
+
+- Change from 4
+
+#### [Merge Request #3](https://gitlab.example.com/group/sub/project/-/merge_requests/3)
+
+- Change from 3
+
+`))
+	})
+
+})
@@ -0,0 +1,183 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"changelog-pr/common"
+
+	"github.com/blang/semver/v4"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// History - Walks the commit history of a git repository between tags
+type History struct {
+	repo *git.Repository
+}
+
+// Range - The commits between a tag and a later revision, newest first
+type Range struct {
+	From    *plumbing.Reference
+	To      plumbing.Hash
+	Commits []*object.Commit
+}
+
+// OpenHistory - Open the git repository at path
+func OpenHistory(path string) (*History, error) {
+	r, err := git.PlainOpen(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository %s: %v", path, err)
+	}
+	return NewHistory(r), nil
+}
+
+// NewHistory - Wrap an already opened repository, e.g. one using memory storage
+func NewHistory(r *git.Repository) *History {
+	return &History{repo: r}
+}
+
+// RemoteURL - The first URL configured for the named remote
+func (h *History) RemoteURL(name string) (string, error) {
+	c, err := h.repo.Config()
+	if err != nil {
+		return "", err
+	}
+	remote, ok := c.Remotes[name]
+	if !ok || len(remote.URLs) == 0 {
+		return "", fmt.Errorf("the repository has no %q remote", name)
+	}
+	return remote.URLs[0], nil
+}
+
+// Tag - The tag reference with the given short name, e.g. v0.1.0
+func (h *History) Tag(name string) (*plumbing.Reference, error) {
+	t, err := h.repo.Tag(strings.TrimPrefix(name, "refs/tags/"))
+	if err != nil {
+		return nil, fmt.Errorf("could not find the TAG %s: %v", name, err)
+	}
+	return t, nil
+}
+
+// LatestTag - The tag with the highest semantic version, tags that do not parse
+// as a semantic version (with an optional v prefix) are ignored
+func (h *History) LatestTag() (*plumbing.Reference, error) {
+	tagrefs, err := h.repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		latest        *plumbing.Reference
+		latestVersion semver.Version
+	)
+	err = tagrefs.ForEach(func(t *plumbing.Reference) error {
+		common.Logger.Debug(fmt.Sprintf("Tag Name: %s", t.Name().String()))
+		v, err := semver.Parse(strings.TrimPrefix(t.Name().Short(), "v"))
+		if err != nil {
+			common.Logger.Debug(fmt.Sprintf("Skipping non SemVer tag %s", t.Name().Short()))
+			return nil
+		}
+		if latest == nil || v.GTE(latestVersion) {
+			latest = t
+			latestVersion = v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		return nil, errors.New("no SemVer tags were found in the repository")
+	}
+	return latest, nil
+}
+
+// commitHash - The commit a tag points at, peeling annotated tags
+func (h *History) commitHash(t *plumbing.Reference) (plumbing.Hash, error) {
+	tag, err := h.repo.TagObject(t.Hash())
+	switch err {
+	case nil:
+		c, err := tag.Commit()
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		return c.Hash, nil
+	case plumbing.ErrObjectNotFound:
+		return t.Hash(), nil
+	default:
+		return plumbing.ZeroHash, err
+	}
+}
+
+// Between - The commits after the fromTag up to and including the toRev revision,
+// ordered newest first by committer time.  An empty fromTag selects the latest
+// SemVer tag and an empty toRev selects HEAD
+func (h *History) Between(fromTag string, toRev string) (*Range, error) {
+	var (
+		from *plumbing.Reference
+		to   plumbing.Hash
+		err  error
+	)
+
+	if len(fromTag) > 0 {
+		from, err = h.Tag(fromTag)
+	} else {
+		from, err = h.LatestTag()
+	}
+	if err != nil {
+		return nil, err
+	}
+	fromHash, err := h.commitHash(from)
+	if err != nil {
+		return nil, err
+	}
+	common.Logger.Info(fmt.Sprintf("Last Tag/Hash: %s (%s)", from.Name().String(), fromHash))
+
+	if len(toRev) > 0 {
+		rev, err := h.repo.ResolveRevision(plumbing.Revision(toRev))
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve %s: %v", toRev, err)
+		}
+		to = *rev
+	} else {
+		head, err := h.repo.Head()
+		if err != nil {
+			return nil, err
+		}
+		common.Logger.Info(fmt.Sprintf("HEAD: %s", head.Name().Short()))
+		toRev = head.Name().Short()
+		to = head.Hash()
+	}
+
+	cIter, err := h.repo.Log(&git.LogOptions{From: to, Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	commits := []*object.Commit{}
+	err = cIter.ForEach(func(c *object.Commit) error {
+		if c.Hash == fromHash {
+			found = true
+			return storer.ErrStop
+		}
+		commits = append(commits, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("the TAG %s is not in the history of %s", from.Name().Short(), toRev)
+	}
+
+	return &Range{
+		From:    from,
+		To:      to,
+		Commits: commits,
+	}, nil
+}
@@ -0,0 +1,147 @@
+package provider_test
+
+import (
+	"fmt"
+	"time"
+
+	"changelog-pr/common"
+	clprovider "changelog-pr/provider"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// memoryRepo - A git repository held in memory, commits are added one at a time
+type memoryRepo struct {
+	repo   *git.Repository
+	hashes map[string]plumbing.Hash
+	count  int
+}
+
+func newMemoryRepo() *memoryRepo {
+	r, err := git.Init(memory.NewStorage(), memfs.New())
+	Expect(err).NotTo(HaveOccurred())
+	return &memoryRepo{repo: r, hashes: map[string]plumbing.Hash{}}
+}
+
+func (m *memoryRepo) commit(message string) plumbing.Hash {
+	w, err := m.repo.Worktree()
+	Expect(err).NotTo(HaveOccurred())
+	f, err := w.Filesystem.Create("file.txt")
+	Expect(err).NotTo(HaveOccurred())
+	_, err = f.Write([]byte(message))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(f.Close()).To(Succeed())
+	_, err = w.Add("file.txt")
+	Expect(err).NotTo(HaveOccurred())
+
+	m.count++
+	when := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(m.count) * time.Hour)
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: when}
+	hash, err := w.Commit(message, &git.CommitOptions{Author: sig, Committer: sig})
+	Expect(err).NotTo(HaveOccurred())
+	m.hashes[message] = hash
+	return hash
+}
+
+func (m *memoryRepo) tag(name string, hash plumbing.Hash) {
+	_, err := m.repo.CreateTag(name, hash, nil)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func messages(commits []*object.Commit) []string {
+	out := []string{}
+	for _, c := range commits {
+		out = append(out, c.Message)
+	}
+	return out
+}
+
+var _ = Describe("History", func() {
+
+	var (
+		m *memoryRepo
+		h *clprovider.History
+	)
+
+	BeforeEach(func() {
+		common.NewLogger("Warn", "")
+		m = newMemoryRepo()
+		m.tag("v0.9.0", m.commit("one"))
+		m.tag("release-candidate", m.commit("two"))
+		m.tag("v0.10.0", m.commit("three"))
+		m.commit("four")
+		m.tag("v0.2.0", m.commit("five"))
+		m.commit("six")
+		h = clprovider.NewHistory(m.repo)
+	})
+
+	It("selects the highest SemVer tag when no tag is given", func() {
+		latest, err := h.LatestTag()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(latest.Name().Short()).To(Equal("v0.10.0"))
+
+		r, err := h.Between("", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.From.Name().Short()).To(Equal("v0.10.0"))
+		Expect(messages(r.Commits)).To(Equal([]string{"six", "five", "four"}))
+	})
+
+	It("returns the commits after an explicit tag, newest first", func() {
+		r, err := h.Between("v0.9.0", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messages(r.Commits)).To(Equal([]string{"six", "five", "four", "three", "two"}))
+	})
+
+	It("stops at an explicit end revision", func() {
+		r, err := h.Between("v0.9.0", "v0.10.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.To).To(Equal(m.hashes["three"]))
+		Expect(messages(r.Commits)).To(Equal([]string{"three", "two"}))
+	})
+
+	It("peels annotated tags to their commit", func() {
+		_, err := m.repo.CreateTag("v0.11.0", m.hashes["five"], &git.CreateTagOptions{
+			Tagger:  &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+			Message: "Release v0.11.0",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		r, err := h.Between("", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.From.Name().Short()).To(Equal("v0.11.0"))
+		Expect(messages(r.Commits)).To(Equal([]string{"six"}))
+	})
+
+	It("fails when the tag is not in the history of HEAD", func() {
+		w, err := m.repo.Worktree()
+		Expect(err).NotTo(HaveOccurred())
+		m.tag("v1.0.0", m.commit("seven"))
+		Expect(w.Reset(&git.ResetOptions{Commit: m.hashes["six"], Mode: git.HardReset})).To(Succeed())
+
+		_, err = h.Between("v1.0.0", "")
+		Expect(err).To(MatchError(ContainSubstring("is not in the history")))
+	})
+
+	It("fails when the tag does not exist", func() {
+		_, err := h.Between("v9.9.9", "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails when there are no SemVer tags", func() {
+		_, err := clprovider.NewHistory(newMemoryRepo().repo).LatestTag()
+		Expect(err).To(MatchError(ContainSubstring("no SemVer tags")))
+	})
+
+	It("fails when the origin remote is missing", func() {
+		_, err := h.RemoteURL("origin")
+		Expect(err).To(MatchError(fmt.Sprintf("the repository has no %q remote", "origin")))
+	})
+
+})
@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"strings"
+)
+
+// LabelRules - How the labels of a PR/MR change its place in the changelog
+type LabelRules struct {
+	// Skip lists the labels that leave a request out of the changelog, e.g. no-changelog
+	Skip []string
+	// Categories file the title of a request under a category when its description
+	// has no changelog entries, the first rule matching a label wins
+	Categories []LabelCategory
+}
+
+// LabelCategory - A label and the changelog category it files requests under
+type LabelCategory struct {
+	Label    string
+	Category string
+}
+
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if strings.EqualFold(strings.TrimSpace(l), strings.TrimSpace(label)) {
+			return true
+		}
+	}
+	return false
+}
+
+// skip - The label that leaves a request out of the changelog, if any
+func (r LabelRules) skip(labels []string) (string, bool) {
+	for _, s := range r.Skip {
+		if hasLabel(labels, s) {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// category - The category a request is filed under by its labels, if any
+func (r LabelRules) category(labels []string) (string, bool) {
+	for _, c := range r.Categories {
+		if hasLabel(labels, c.Label) {
+			return c.Category, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,115 @@
+package provider_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"changelog-pr/common"
+	clprovider "changelog-pr/provider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Labels", func() {
+
+	var (
+		server *testServer
+		repo   string
+		rules  clprovider.LabelRules
+	)
+
+	BeforeEach(func() {
+		rules = clprovider.LabelRules{
+			Skip: []string{"no-changelog"},
+			Categories: []clprovider.LabelCategory{
+				{Label: "type:feature", Category: common.ADDITIONS},
+				{Label: "type:bug", Category: common.BUGFIXES},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(repo)
+	})
+
+	Describe("GitHub", func() {
+
+		BeforeEach(func() {
+			server = newTestServer(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v3/repos/owner/repo/pulls/1":
+					fmt.Fprint(w, `{"title": "Bump the linter", "body": "## Changelog Inclusions\n\n### Changes\n\n- Bumped the linter\n", "labels": [{"name": "No-Changelog"}], "_links": {"html": {"href": "https://github.example.internal/owner/repo/pull/1"}}}`)
+				case "/api/v3/repos/owner/repo/pulls/2":
+					fmt.Fprint(w, `{"title": "Fix the login form", "body": "Small fix", "labels": [{"name": "type:bug"}], "_links": {"html": {"href": "https://github.example.internal/owner/repo/pull/2"}}}`)
+				case "/api/v3/repos/owner/repo/pulls/3":
+					fmt.Fprint(w, `{"title": "Add dark mode", "body": "## Changelog Inclusions\n\n### Changes\n\n- Themes are configurable\n", "labels": [{"name": "type:feature"}], "_links": {"html": {"href": "https://github.example.internal/owner/repo/pull/3"}}}`)
+				case "/api/v3/repos/owner/repo/pulls/4":
+					fmt.Fprint(w, `{"title": "Refactor", "body": "No section", "labels": [], "_links": {"html": {"href": "https://github.example.internal/owner/repo/pull/4"}}}`)
+				default:
+					http.NotFound(w, r)
+				}
+			})
+			repo = newTestRepo("git@github.example.internal:owner/repo.git",
+				testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+				testCommit{Message: "Merge pull request #4 from owner/refactor"},
+				testCommit{Message: "Merge pull request #3 from owner/dark"},
+				testCommit{Message: "Merge pull request #2 from owner/login"},
+				testCommit{Message: "Merge pull request #1 from owner/lint"},
+			)
+		})
+
+		It("skips and categorizes pull requests by their labels", func() {
+			gp := server.provider(clprovider.GITHUB, clprovider.Options{Labels: rules})
+
+			changeLog, err := gp.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changeLog.Count()).To(Equal(2))
+			Expect(changeLog.Entries(common.BUGFIXES)).To(HaveLen(1))
+			Expect(changeLog.Entries(common.BUGFIXES)[0].Description).To(Equal("- Fix the login form\n"))
+			Expect(changeLog.Entries(common.BUGFIXES)[0].Number).To(Equal("2"))
+			Expect(changeLog.Entries(common.BUGFIXES)[0].Labels).To(Equal([]string{"type:bug"}))
+			Expect(changeLog.Entries(common.CHANGES)).To(HaveLen(1))
+			Expect(changeLog.Entries(common.CHANGES)[0].Description).To(Equal("- Themes are configurable\n"))
+			Expect(changeLog.Entries(common.ADDITIONS)).To(BeEmpty())
+		})
+
+	})
+
+	Describe("GitLab", func() {
+
+		BeforeEach(func() {
+			server = newTestServer(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v4/projects/owner%2Frepo/merge_requests/5", "/api/v4/projects/owner/repo/merge_requests/5":
+					fmt.Fprint(w, `{"title": "Add the export", "description": "", "labels": ["type:feature"], "web_url": "https://gitlab.example.com/owner/repo/-/merge_requests/5"}`)
+				case "/api/v4/projects/owner%2Frepo/merge_requests/6", "/api/v4/projects/owner/repo/merge_requests/6":
+					fmt.Fprint(w, `{"title": "Tidy", "description": "", "labels": ["no-changelog", "type:feature"], "web_url": "https://gitlab.example.com/owner/repo/-/merge_requests/6"}`)
+				default:
+					http.NotFound(w, r)
+				}
+			})
+			repo = newTestRepo("git@gitlab.example.com:owner/repo.git",
+				testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+				testCommit{Message: "Merge branch 'export' into 'main'\n\nSee merge request owner/repo!5"},
+				testCommit{Message: "Merge branch 'tidy' into 'main'\n\nSee merge request owner/repo!6"},
+			)
+		})
+
+		It("skips and categorizes merge requests by their labels", func() {
+			gp := server.provider(clprovider.GITLAB, clprovider.Options{Labels: rules})
+
+			changeLog, err := gp.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changeLog.Count()).To(Equal(1))
+			Expect(changeLog.Entries(common.ADDITIONS)).To(HaveLen(1))
+			Expect(changeLog.Entries(common.ADDITIONS)[0].Description).To(Equal("- Add the export\n"))
+			Expect(changeLog.Entries(common.ADDITIONS)[0].Kind).To(Equal("Merge Request"))
+			Expect(changeLog.Entries(common.ADDITIONS)[0].Number).To(Equal("5"))
+		})
+
+	})
+
+})
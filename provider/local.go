@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"strings"
+
+	"changelog-pr/common"
+)
+
+// Local - Reads the changelog sections from the commit messages themselves, for
+// repositories without a PR/MR workflow.  No provider API is called
+type Local struct {
+	Provider string
+	Host     string
+	Options  Options
+}
+
+// commitURL - Fill the {sha} and {short} placeholders of the commit URL pattern,
+// an empty pattern produces no URL
+func (p *Local) commitURL(sha string) string {
+	if len(p.Options.CommitURL) == 0 {
+		return ""
+	}
+	r := strings.NewReplacer("{sha}", sha, "{short}", shortHash(sha))
+	return r.Replace(p.Options.CommitURL)
+}
+
+func shortHash(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// GetChangelog - Collect the changelog from the commit messages since sinceTag
+func (p *Local) GetChangelog(src string, sinceTag string, release string, auth AuthToken) (*common.Changelog, error) {
+	h, err := OpenHistory(src)
+	if err != nil {
+		return nil, err
+	}
+
+	commitRange, err := h.Between(sinceTag, "")
+	if err != nil {
+		return nil, err
+	}
+
+	changeLog := common.NewChangelog(release, p.Options.Categories)
+	changeLog.Since = commitRange.From.Name().Short()
+
+	requests := []*Request{}
+	for _, c := range commitRange.Commits {
+		common.Logger.Trace(c.Message)
+		sha := c.Hash.String()
+		// the subject of a commit is its title, the rest of the message its body
+		message := strings.SplitN(strings.TrimLeft(c.Message, "\n"), "\n", 2)
+		request := &Request{
+			Title:    message[0],
+			URL:      p.commitURL(sha),
+			Author:   c.Author.Name,
+			MergedAt: c.Committer.When,
+			MergeSHA: sha,
+			ref:      shortHash(sha),
+		}
+		if len(message) > 1 {
+			request.Body = message[1]
+		}
+		requests = append(requests, request)
+	}
+
+	if err := p.Options.collectRequests(changeLog, requests, "Commit"); err != nil {
+		return nil, err
+	}
+
+	return changeLog, nil
+}
+
+// GetChangeLogFromPRMR - Get the changelog details from the commit messages
+func (p *Local) GetChangeLogFromPRMR(src string, sinceTag string, release string, auth AuthToken, fileName string) (string, error) {
+	return render(p, p.Options, src, sinceTag, release, auth, fileName)
+}
@@ -0,0 +1,67 @@
+package provider_test
+
+import (
+	"os"
+	"time"
+
+	"changelog-pr/common"
+	clprovider "changelog-pr/provider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Local", func() {
+
+	var repo string
+
+	BeforeEach(func() {
+		common.NewLogger("Warn", "")
+		repo = newTestRepo("",
+			testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+			testCommit{Message: "Add the widget\n\n## Changelog Inclusions\n\n### Additions\n\n- Added the widget\n"},
+			testCommit{Message: "Tidy up"},
+			testCommit{Message: "Fix the widget\n\n## Changelog Inclusions\n\n### Fixes\n\n- Fixed the widget\n"},
+		)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(repo)
+	})
+
+	It("collects the sections from commit messages without a remote", func() {
+		p, err := clprovider.GetProvider(clprovider.LOCAL, "", clprovider.Options{CommitURL: "https://git.example.com/owner/repo/commit/{sha}?short={short}"})
+		Expect(err).NotTo(HaveOccurred())
+
+		changeLog, err := p.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changeLog.Version).To(Equal("v0.2.0"))
+		Expect(changeLog.Entries(common.ADDITIONS)).To(HaveLen(1))
+		Expect(changeLog.Entries(common.ADDITIONS)[0].Description).To(Equal("- Added the widget\n"))
+		entry := changeLog.Entries(common.ADDITIONS)[0]
+		Expect(entry.Kind).To(Equal("Commit"))
+		Expect(entry.Number).To(MatchRegexp(`^[0-9a-f]{7}$`))
+		Expect(entry.MergeSHA).To(HavePrefix(entry.Number))
+		Expect(entry.URL).To(Equal("https://git.example.com/owner/repo/commit/" + entry.MergeSHA + "?short=" + entry.Number))
+		Expect(entry.Title).To(Equal("Add the widget"))
+		Expect(entry.Author).To(Equal("Test"))
+		Expect(entry.MergedAt.UTC()).To(Equal(time.Date(2021, 3, 1, 1, 0, 0, 0, time.UTC)))
+		Expect(changeLog.Entries(common.BUGFIXES)).To(HaveLen(1))
+		Expect(changeLog.Entries(common.BUGFIXES)[0].Description).To(Equal("- Fixed the widget\n"))
+	})
+
+	It("names the commit when no commit URL is configured", func() {
+		p, err := clprovider.GetProvider(clprovider.LOCAL, "", clprovider.Options{})
+		Expect(err).NotTo(HaveOccurred())
+
+		changeLog, err := p.GetChangelog(repo, "", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changeLog.Entries(common.ADDITIONS)).To(HaveLen(1))
+		Expect(changeLog.Entries(common.ADDITIONS)[0].URL).To(BeEmpty())
+
+		out, err := p.GetChangeLogFromPRMR(repo, "", "v0.2.0", clprovider.AuthToken{}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(MatchRegexp("\n#### Commit #[0-9a-f]{7}\n"))
+	})
+
+})
@@ -0,0 +1,82 @@
+package provider_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"changelog-pr/common"
+	clprovider "changelog-pr/provider"
+
+	"github.com/go-git/go-git/v5"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Entry metadata", func() {
+
+	var (
+		server *testServer
+		repo   string
+	)
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(repo)
+	})
+
+	It("records the pull request metadata reported by GitHub", func() {
+		server = newTestServer(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"title": "Add the widget", "body": "## Changelog Inclusions\n\n### Additions\n\n- Widget\n", "labels": [{"name": "ui"}, {"name": "type:feature"}], "user": {"login": "octocat"}, "merged_at": "2021-03-02T10:30:00Z", "merge_commit_sha": "6dcb09b5b57875f334f61aebed695e2e4193db5e", "_links": {"html": {"href": "https://github.example.internal/owner/repo/pull/7"}}}`)
+		})
+		repo = newTestRepo("git@github.example.internal:owner/repo.git",
+			testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+			testCommit{Message: "Merge pull request #7 from owner/widget"},
+		)
+
+		gp := server.provider(clprovider.GITHUB, clprovider.Options{})
+		changeLog, err := gp.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(changeLog.Entries(common.ADDITIONS)).To(Equal([]common.ChangelogEntry{{
+			Description: "- Widget\n",
+			Request: common.Request{
+				Kind:     "Pull Request",
+				Number:   "7",
+				Title:    "Add the widget",
+				URL:      "https://github.example.internal/owner/repo/pull/7",
+				Author:   "octocat",
+				MergedAt: time.Date(2021, 3, 2, 10, 30, 0, 0, time.UTC),
+				Labels:   []string{"ui", "type:feature"},
+				MergeSHA: "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+			},
+		}}))
+	})
+
+	It("takes the merge commit from the history when the provider does not report it", func() {
+		server = newTestServer(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"title": "Fix the widget", "description": "## Changelog Inclusions\n\n### Fixes\n\n- Widget\n", "author": {"username": "tanuki"}, "merged_at": null, "merge_commit_sha": null, "web_url": "https://gitlab.example.com/owner/repo/-/merge_requests/3"}`)
+		})
+		repo = newTestRepo("git@gitlab.example.com:owner/repo.git",
+			testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+			testCommit{Message: "Merge branch 'fix' into 'main'\n\nSee merge request owner/repo!3"},
+		)
+		r, err := git.PlainOpen(repo)
+		Expect(err).NotTo(HaveOccurred())
+		head, err := r.Head()
+		Expect(err).NotTo(HaveOccurred())
+
+		gp := server.provider(clprovider.GITLAB, clprovider.Options{})
+		changeLog, err := gp.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(changeLog.Entries(common.BUGFIXES)).To(HaveLen(1))
+		entry := changeLog.Entries(common.BUGFIXES)[0]
+		Expect(entry.Author).To(Equal("tanuki"))
+		Expect(entry.MergeSHA).To(Equal(head.Hash().String()))
+		Expect(entry.MergedAt.UTC()).To(Equal(time.Date(2021, 3, 1, 1, 0, 0, 0, time.UTC)))
+	})
+
+})
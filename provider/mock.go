@@ -1,7 +1,6 @@
 package provider
 
 import (
-	"errors"
 	"fmt"
 
 	"changelog-pr/common"
@@ -11,17 +10,17 @@ import (
 type Mock struct {
 	Provider string
 	Host     string
+	Options  Options
 }
 
-// GetChangeLogSincePRMR - Get the changelog details from the PR/MR description
-func (p *Mock) GetChangeLogFromPRMR(src string, sincePR string, release string, auth AuthToken, fileName string) (string, error) {
+// GetChangelog - Collect the changelog from canned PR descriptions, selected by sincePR
+func (p *Mock) GetChangelog(src string, sincePR string, release string, auth AuthToken) (*common.Changelog, error) {
 
 	var (
 		PRData []string
 	)
 
-	changeLog := common.Changelog{}
-	changeLog.Version = release
+	changeLog := common.NewChangelog(release, p.Options.Categories)
 
 	switch sincePR {
 	case "v0.0.1":
@@ -145,24 +144,21 @@ This is the description
 	}
 
 	for k, v := range PRData {
-		err := common.ParseMarkdown(v, fmt.Sprintf("%d", k), &changeLog, "Pull Request", "https://github.com/example/repo/mr/1")
+		request := common.Request{
+			Kind:   "Pull Request",
+			Number: fmt.Sprintf("%d", k),
+			URL:    fmt.Sprintf("https://github.com/splicemachine/splicectl/pull/%d", k),
+		}
+		err := common.ParseMarkdown(v, request, changeLog, p.Options.Boilerplate)
 		if err != nil {
 			common.Logger.Error("Could not parse the markdown")
 		}
 	}
 
-	markdown, err := changeLog.Template()
-	if err != nil {
-		return "", errors.New("failed generation of changelog")
-	}
-
-	if len(fileName) > 0 {
-		err := changeLog.WriteFile(fileName)
-		if err != nil {
-			return "", errors.New("failed to write to the output file")
-		}
-		return "Changelog data has been saved.", nil
-	}
+	return changeLog, nil
+}
 
-	return string(markdown[:]), nil
+// GetChangeLogFromPRMR - Get the changelog details from the PR/MR description
+func (p *Mock) GetChangeLogFromPRMR(src string, sincePR string, release string, auth AuthToken, fileName string) (string, error) {
+	return render(p, p.Options, src, sincePR, release, auth, fileName)
 }
@@ -3,15 +3,18 @@ package provider
 //import errors to log errors when they occur
 import (
 	"errors"
+	"fmt"
 	"regexp"
+	"strings"
+	"text/template"
+	"time"
 
-	// . "github.com/go-git/go-git/v5/_examples"
-
-	"github.com/go-git/go-git/v5/plumbing"
+	"changelog-pr/common"
 )
 
 // Provider = The main interface used to describe appliances
 type Provider interface {
+	GetChangelog(sourcePath string, sinceTag string, releaseTag string, auth AuthToken) (*common.Changelog, error)
 	GetChangeLogFromPRMR(sourcePath string, sinceTag string, releaseTag string, auth AuthToken, fileName string) (string, error)
 }
 
@@ -19,33 +22,182 @@ type AuthToken struct {
 	AccessToken string
 }
 
-var lastTag *plumbing.Reference
+// Options - Behaviour settings shared by the providers
+type Options struct {
+	// Strategies maps a lower case "user/repo" to the merge detection strategies
+	// used for that repository, the "default" key applies to every other repository
+	Strategies map[string][]string
+	// Concurrency is the number of PR/MR descriptions fetched, or commits looked
+	// up, at the same time
+	Concurrency int
+	// Retries is the number of times a rate limited or failed API call is retried
+	Retries int
+	// RetryWait is the initial backoff between retries, doubled on every attempt
+	RetryWait time.Duration
+	// RetryMaxWait caps any single wait, including waits for a rate limit reset
+	RetryMaxWait time.Duration
+	// Cache keeps fetched PR/MR descriptions between runs, nil disables caching
+	Cache *Cache
+	// Record saves every API request and response as fixture files in this directory
+	Record string
+	// Replay serves API responses from the fixture files in this directory instead
+	// of the network
+	Replay string
+	// CommitURL is the link pattern for commits read by the local provider, the
+	// {sha} and {short} placeholders are replaced with the full and short hash
+	CommitURL string
+	// Categories are the changelog sections, nil selects common.DefaultCategories
+	Categories []common.Category
+	// Boilerplate is the placeholder text left out of the changelog, nil selects
+	// the built in PR template
+	Boilerplate *common.Boilerplate
+	// Labels are the rules for requests carrying certain labels
+	Labels LabelRules
+	// Fallback selects what is added for a request without changelog entries,
+	// nothing when empty or its TITLE
+	Fallback string
+	// FallbackCategory is the category fallback entries are filed under, an
+	// empty one selects common.UncategorizedCategory
+	FallbackCategory string
+	// Template renders the changelog, nil selects the built in template
+	Template *template.Template
+	// Format selects the output format, common.MARKDOWN (the default), common.JSON
+	// or common.YAML
+	Format string
+	// KeepAChangelog, when set, adds Markdown changelogs to the output file as a
+	// section of a keepachangelog.com file instead of prepending them
+	KeepAChangelog *common.KeepAChangelog
+	// OnExisting selects what happens when the output file already has a section
+	// for the release, common.REPLACE (the default), SKIP, FAIL or APPEND.  The
+	// KeepAChangelog file carries its own
+	OnExisting string
+	// Parser selects how the changelog is read from a request, MARKDOWN (the
+	// default) or CONVENTIONAL
+	Parser string
+	// ConventionalTypes maps Conventional Commits types onto changelog categories,
+	// nil selects common.DefaultConventionalTypes
+	ConventionalTypes map[string]string
+}
+
+// strategiesFor - The merge detection strategies configured for a repository,
+// falling back to the provider defaults when nothing is configured
+func (o Options) strategiesFor(user string, repo string, defaults []string) []string {
+	if s, ok := o.Strategies[strings.ToLower(fmt.Sprintf("%s/%s", user, repo))]; ok && len(s) > 0 {
+		return s
+	}
+	if s, ok := o.Strategies["default"]; ok && len(s) > 0 {
+		return s
+	}
+	return defaults
+}
+
+func hasStrategy(strategies []string, strategy string) bool {
+	for _, s := range strategies {
+		if strings.EqualFold(strings.TrimSpace(s), strategy) {
+			return true
+		}
+	}
+	return false
+}
+
+var remoteRegex = regexp.MustCompile(`^(?:[a-z+]+://)?(?:[^@/]+@)?[^/:]+(?::\d+)?[:/](.+)/([^/]+?)(?:\.git)?/?$`)
 var numRegex = regexp.MustCompile(`#(\d+) from`)
-var numBangRegex = regexp.MustCompile(`!(\d+)$`)
+var numSquashRegex = regexp.MustCompile(`\(#(\d+)\)$`)
+var numMergeRequestRegex = regexp.MustCompile(`See merge request \S*!(\d+)`)
+var numBitbucketRegex = regexp.MustCompile(`\(pull request #(\d+)\)`)
+var numGiteaRegex = regexp.MustCompile(`\(#(\d+)\) from `)
+var numAzureRegex = regexp.MustCompile(`^Merged PR (\d+):`)
 
 // Provider Types
 const (
-	GITHUB = "github"
-	GITLAB = "gitlab"
-	MOCK   = "mock"
+	GITHUB    = "github"
+	GITLAB    = "gitlab"
+	BITBUCKET = "bitbucket"
+	GITEA     = "gitea"
+	AZURE     = "azure"
+	LOCAL     = "local"
+	MOCK      = "mock"
+)
+
+// Merge detection strategies
+const (
+	// MERGE - Merge commits created by the provider, e.g. "Merge pull request #12 from ..."
+	MERGE = "merge"
+	// SQUASH - Squash merged commits where the subject ends in "(#12)" (GitHub)
+	SQUASH = "squash"
+	// LOOKUP - Ask the provider API which requests contain any other commit
+	LOOKUP = "lookup"
+)
+
+// Parsers
+const (
+	// MARKDOWN - The "## Changelog Inclusions" sections of a PR/MR description or commit message
+	MARKDOWN = "markdown"
+	// CONVENTIONAL - The Conventional Commits header of a PR/MR title or commit message
+	CONVENTIONAL = "conventional"
 )
 
+// Fallbacks
+const (
+	// TITLE - File the title of a request without changelog entries
+	TITLE = "title"
+)
+
+// hostURL - Prefix a bare host name with https://, a host that already carries
+// a scheme (e.g. http://localhost:3000) is used as is
+func hostURL(host string) string {
+	if strings.Contains(host, "://") {
+		return strings.TrimSuffix(host, "/")
+	}
+	return fmt.Sprintf("https://%s", host)
+}
+
 // GetProvider - Function to create the appliances
-func GetProvider(t string, h string) (Provider, error) {
+func GetProvider(t string, h string, o Options) (Provider, error) {
 	//Use a switch case to switch between types, if a type exist then error is nil (null)
 	switch t {
 	case GITHUB:
 		return &Github{
 			Provider: "github",
 			Host:     h,
+			Options:  o,
 		}, nil
 	case GITLAB:
 		return &Gitlab{
 			Provider: "gitlab",
 			Host:     h,
+			Options:  o,
+		}, nil
+	case BITBUCKET:
+		return &Bitbucket{
+			Provider: "bitbucket",
+			Host:     h,
+			Options:  o,
+		}, nil
+	case GITEA:
+		return &Gitea{
+			Provider: "gitea",
+			Host:     h,
+			Options:  o,
+		}, nil
+	case AZURE:
+		return &Azure{
+			Provider: "azure",
+			Host:     h,
+			Options:  o,
+		}, nil
+	case LOCAL:
+		return &Local{
+			Provider: "local",
+			Host:     h,
+			Options:  o,
 		}, nil
 	case MOCK:
-		return new(Mock), nil
+		return &Mock{
+			Provider: "mock",
+			Host:     h,
+			Options:  o,
+		}, nil
 	default:
 		//if type is invalid, return an error
 		return nil, errors.New("unsupported provider")
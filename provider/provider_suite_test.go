@@ -0,0 +1,118 @@
+package provider_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"changelog-pr/common"
+	clprovider "changelog-pr/provider"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestProvider(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Provider Suite")
+}
+
+var _ = BeforeEach(func() {
+	common.NewLogger("Warn", "")
+})
+
+// testCommit describes a commit to be created by newTestRepo, when Tag is set
+// a lightweight tag is created pointing at the commit
+type testCommit struct {
+	Message string
+	Tag     string
+}
+
+// newTestRepo - Create a git repository on disk with the given origin remote,
+// none when remote is empty, and a linear history built from commits, oldest first
+func newTestRepo(remote string, commits ...testCommit) string {
+	dir, err := ioutil.TempDir("", "changelog-pr")
+	Expect(err).NotTo(HaveOccurred())
+
+	r, err := git.PlainInit(dir, false)
+	Expect(err).NotTo(HaveOccurred())
+	if len(remote) > 0 {
+		_, err = r.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remote}})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	w, err := r.Worktree()
+	Expect(err).NotTo(HaveOccurred())
+
+	when := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i, c := range commits {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "file.txt"), []byte(fmt.Sprintf("%d", i)), 0644)).To(Succeed())
+		_, err = w.Add("file.txt")
+		Expect(err).NotTo(HaveOccurred())
+		sig := &object.Signature{Name: "Test", Email: "test@example.com", When: when.Add(time.Duration(i) * time.Hour)}
+		hash, err := w.Commit(c.Message, &git.CommitOptions{Author: sig, Committer: sig})
+		Expect(err).NotTo(HaveOccurred())
+		if len(c.Tag) > 0 {
+			_, err = r.CreateTag(c.Tag, hash, nil)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	}
+	return dir
+}
+
+// testServer - A provider API answering with handler, which records the path and
+// the Authorization header of every request
+type testServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	paths []string
+	auth  []string
+}
+
+// newTestServer - Start a testServer, it is stopped with Close
+func newTestServer(handler http.HandlerFunc) *testServer {
+	s := &testServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.paths = append(s.paths, r.URL.EscapedPath())
+		s.auth = append(s.auth, r.Header.Get("Authorization"))
+		s.mu.Unlock()
+		handler(w, r)
+	}))
+	return s
+}
+
+// Paths - The escaped paths of the requests so far, in the order they arrived
+func (s *testServer) Paths() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.paths...)
+}
+
+// Auth - The Authorization headers of the requests so far
+func (s *testServer) Auth() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.auth...)
+}
+
+// Hits - The number of requests so far
+func (s *testServer) Hits() int {
+	return len(s.Paths())
+}
+
+// provider - A provider of type t with the server as its host
+func (s *testServer) provider(t string, o clprovider.Options) clprovider.Provider {
+	p, err := clprovider.GetProvider(t, s.URL, o)
+	Expect(err).NotTo(HaveOccurred())
+	return p
+}
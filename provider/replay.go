@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// errNoFixture - Replay has no recorded response for a request, retrying will not help
+var errNoFixture = errors.New("no recorded response")
+
+// fixture - A recorded API request and the response the provider gave
+type fixture struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// fixturePath - The file a request is recorded to, named from its method and URL
+func fixturePath(dir string, req *http.Request) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s %s", req.Method, req.URL.String())))
+	return filepath.Join(dir, fmt.Sprintf("%s.json", hex.EncodeToString(sum[:])[:16]))
+}
+
+// recordTransport - Sends requests to the provider and saves every request and
+// response as a fixture file in dir
+type recordTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	data, err := json.MarshalIndent(fixture{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Body:   string(body),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(t.dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(fixturePath(t.dir, req), data, 0644); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// replayTransport - Serves the responses saved by recordTransport without any
+// network access
+type replayTransport struct {
+	dir string
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	data, err := ioutil.ReadFile(fixturePath(t.dir, req))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w for %s %s in %s", errNoFixture, req.Method, req.URL, t.dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("could not read the fixture for %s %s: %v", req.Method, req.URL, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		StatusCode:    f.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(f.Body))),
+		ContentLength: int64(len(f.Body)),
+		Request:       req,
+	}, nil
+}
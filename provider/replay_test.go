@@ -0,0 +1,78 @@
+package provider_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	clprovider "changelog-pr/provider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Record and replay", func() {
+
+	var (
+		server     *testServer
+		repo       string
+		fixtureDir string
+	)
+
+	BeforeEach(func() {
+		server = newTestServer(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"description": "## Changelog Inclusions\n\n### Removed\n\n- Recorded removal\n", "web_url": "https://gitlab.example.com/owner/repo/-/merge_requests/2"}`)
+		})
+		repo = newTestRepo("git@gitlab.example.com:owner/repo.git",
+			testCommit{Message: "Initial commit", Tag: "v0.1.0"},
+			testCommit{Message: "Merge branch 'cleanup' into 'main'\n\nSee merge request owner/repo!2"},
+			testCommit{Message: "Merge branch 'more' into 'main'\n\nSee merge request owner/repo!3"},
+		)
+		var err error
+		fixtureDir, err = ioutil.TempDir("", "changelog-pr-fixtures")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(repo)
+		os.RemoveAll(fixtureDir)
+	})
+
+	It("replays a recorded run without the network", func() {
+		recorder := server.provider(clprovider.GITLAB, clprovider.Options{Record: fixtureDir})
+		recorded, err := recorder.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{AccessToken: "secret"}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(server.Hits()).To(Equal(2))
+
+		fixtures, err := filepath.Glob(filepath.Join(fixtureDir, "*.json"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fixtures).To(HaveLen(2))
+		for _, f := range fixtures {
+			data, err := ioutil.ReadFile(f)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).NotTo(ContainSubstring("secret"))
+		}
+
+		serverURL := server.URL
+		server.Close()
+
+		player, err := clprovider.GetProvider(clprovider.GITLAB, serverURL, clprovider.Options{Replay: fixtureDir, Retries: 3})
+		Expect(err).NotTo(HaveOccurred())
+		replayed, err := player.GetChangeLogFromPRMR(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(replayed).To(Equal(recorded))
+		Expect(server.Hits()).To(Equal(2))
+	})
+
+	It("fails without retrying when a response was not recorded", func() {
+		player := server.provider(clprovider.GITLAB, clprovider.Options{Replay: fixtureDir, Retries: 3})
+		_, err := player.GetChangelog(repo, "v0.1.0", "v0.2.0", clprovider.AuthToken{})
+		Expect(err).To(MatchError(ContainSubstring("no recorded response")))
+		Expect(err).To(MatchError(ContainSubstring("failed after 1 attempt(s)")))
+		Expect(server.Hits()).To(Equal(0))
+	})
+
+})